@@ -10,41 +10,174 @@
 //	# Publish metrics to an OTEL collector
 //	tfwatch --dir ./infra --otel-endpoint otel.example.com:4317
 //
+//	# Scan every root module in a monorepo
+//	tfwatch scan --recursive ./infra
+//
+//	# Compare dependencies against the latest registry versions
+//	tfwatch outdated --list ./infra
+//
+//	# Fail the build on policy violations
+//	tfwatch check --policy policy.yaml --list ./infra
+//
+//	# Check the installed provider packages against the lock file hashes
+//	tfwatch verify ./infra
+//
+//	# Rescan every 10 minutes and serve Prometheus metrics on :9464
+//	tfwatch serve --recursive ./infra
+//
+//	# Re-publish a root whenever its lock file, modules.json or *.tf change
+//	tfwatch watch --recursive ./infra
+//
+//	# Deliver metrics that an earlier run could not
+//	tfwatch flush-spool --spool-dir /var/spool/tfwatch
+//
+//	# Publish to a Prometheus Pushgateway or a node_exporter textfile
+//	tfwatch --pushgateway http://pushgateway:9091 ./infra
+//	tfwatch --prom-file /var/lib/node_exporter/tfwatch.prom ./infra
+//
+//	# Write metrics to a file on an air-gapped host, then push it elsewhere
+//	tfwatch --otel-file metrics.jsonl ./infra
+//	tfwatch publish --from metrics.jsonl
+//
 //	# Show version
 //	tfwatch --version
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/CloudPulse-HQ/tfwatch/internal/tfwatch"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
-	"google.golang.org/grpc/credentials"
 )
 
 var version = "dev"
 
 // Config holds CLI flag values for a tfwatch run.
 type Config struct {
+	Command      string // "scan", "outdated", "check", "verify", "serve", "watch", "flush-spool" or "publish"
 	Directory    string
 	Phase        string // "plan" or "apply"
 	OTELEndpoint string
 	OTELInsecure bool
 	ListOnly     bool
+	Recursive    bool
+	Include      []string
+	Exclude      []string
+
+	OTELProtocol       string // "grpc", "http/protobuf" or "http/json"
+	OTELPath           string // URL path for the HTTP protocols
+	OTELCompression    string // "gzip" or "none"
+	OTELHeaders        map[string]string
+	OTELTimeout        time.Duration
+	OTELDeadline       time.Duration     // how long failed exports are retried
+	OTELFile           string            // write OTLP/JSON lines here instead of pushing
+	Pushgateway        string            // push to this Prometheus Pushgateway instead
+	PushgatewayJob     string            // job label of pushed metrics
+	PushgatewayHeaders map[string]string // sent with every push; OTLP headers are not
+	PromFile           string            // write a node_exporter textfile instead
+
+	OTELCAFile     string // PEM CA bundle for verifying the collector
+	OTELClientCert string // PEM client certificate for mutual TLS
+	OTELClientKey  string
+	OTELServerName string // overrides the name verified in the collector's certificate
+
+	Concurrency     int
+	InitConcurrency int
+	NoInit          bool // read module blocks instead of running terraform init
+
+	Output string // "text", "json", "yaml", "cyclonedx" or "spdx"
+
+	RegistryTimeout time.Duration // per-request timeout for "outdated" and the platforms rules of "check"
+	VersionIndex    string        // offline version index file
+	StateFile       string        // series published by the previous run, to retire stale ones
+	SpoolDir        string        // undelivered payloads, replayed by the next run
+	PublishFrom     string        // --otel-file output sent by "publish"
+
+	Policy string // policy file for "check"
+	FailOn string // lowest violation severity that fails "check"
+
+	Listen   string        // address of the /metrics endpoint for "serve"
+	Interval time.Duration // time between scans for "serve"
+	OTELPush bool          // also push each "serve" scan via OTLP
+
+	Debounce time.Duration // quiet period before "watch" rescans a root
+
+	versions tfwatch.VersionSource // loaded from VersionIndex by main
+}
+
+// commands are the names accepted as the first argument.
+var commands = []string{"scan", "outdated", "check", "verify", "serve", "watch", "flush-spool", "publish"}
+
+// flagSet reports whether the flag called name was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
 }
 
 func main() {
 	cfg := parseFlags()
+	if cfg.VersionIndex != "" {
+		index, err := tfwatch.LoadVersionIndex(cfg.VersionIndex)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.versions = index
+	}
+
+	switch cfg.Command {
+	case "outdated":
+		os.Exit(runOutdated(cfg))
+	case "check":
+		os.Exit(runCheck(cfg))
+	case "verify":
+		os.Exit(runVerify(cfg))
+	case "serve":
+		os.Exit(runServe(cfg))
+	case "watch":
+		os.Exit(runWatch(cfg))
+	case "flush-spool":
+		os.Exit(runFlushSpool(cfg))
+	case "publish":
+		os.Exit(runPublish(cfg))
+	}
+	if cfg.Output != "text" {
+		os.Exit(runStructured(cfg))
+	}
 	printBanner()
 
+	if cfg.Recursive {
+		os.Exit(runRecursive(cfg))
+	}
+
 	if cfg.ListOnly {
 		if err := listDependencies(cfg); err != nil {
 			log.Fatal(err)
@@ -53,26 +186,395 @@ func main() {
 	}
 
 	ctx := context.Background()
-	shutdown, err := initOTEL(ctx, cfg)
+	tel, err := initOTEL(ctx, cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize OTEL: %v", err)
 	}
-	defer func() { _ = shutdown(ctx) }()
+	defer func() { _ = tel.Shutdown(ctx) }()
 
+	state, err := loadState(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 	collector := tfwatch.NewCollector(tfwatch.CollectorConfig{
 		Directory:    cfg.Directory,
 		Phase:        cfg.Phase,
-		OTELEndpoint: cfg.OTELEndpoint,
+		OTELEndpoint: otelTarget(cfg),
+		Versions:     cfg.versions,
+		Concurrency:  cfg.Concurrency,
+		NoInit:       cfg.NoInit,
+		State:        state,
 	})
 	if err := collector.Collect(ctx); err != nil {
 		log.Fatalf("Failed to collect dependencies: %v", err)
 	}
+	if err := tel.Flush(ctx); err != nil {
+		log.Print(err)
+		_ = tel.Shutdown(ctx)
+		os.Exit(exitUndelivered)
+	}
+	if err := saveState(cfg, state); err != nil {
+		log.Fatal(err)
+	}
 
-	fmt.Println("\nDone. Metrics published to", cfg.OTELEndpoint)
+	fmt.Println("\nDone. Metrics published to", otelTarget(cfg))
 }
 
 func listDependencies(cfg Config) error {
-	return tfwatch.ListDependencies(cfg.Directory)
+	return tfwatch.ListDependencies(cfg.Directory, scanOptions(cfg))
+}
+
+func scanOptions(cfg Config) tfwatch.ScanOptions {
+	return tfwatch.ScanOptions{
+		Concurrency:     cfg.Concurrency,
+		InitConcurrency: cfg.InitConcurrency,
+		NoInit:          cfg.NoInit,
+	}
+}
+
+// discoverRoots returns the root module directories selected by cfg.
+func discoverRoots(cfg Config) ([]string, error) {
+	roots, err := tfwatch.DiscoverRoots(cfg.Directory, tfwatch.DiscoverOptions{
+		Include: cfg.Include,
+		Exclude: cfg.Exclude,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover root modules: %w", err)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no Terraform root modules found in %s", cfg.Directory)
+	}
+	return roots, nil
+}
+
+// runRecursive discovers every root module under cfg.Directory and lists or
+// publishes them in a single run. It returns the process exit code.
+func runRecursive(cfg Config) int {
+	roots, err := discoverRoots(cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	fmt.Printf("Discovered %d root module(s) in %s\n", len(roots), cfg.Directory)
+
+	opts := scanOptions(cfg)
+
+	ctx := context.Background()
+	var summary *tfwatch.ScanSummary
+	undelivered := false
+	if cfg.ListOnly {
+		summary = tfwatch.ListRoots(ctx, cfg.Directory, roots, opts)
+	} else {
+		tel, err := initOTEL(ctx, cfg)
+		if err != nil {
+			log.Printf("Failed to initialize OTEL: %v", err)
+			return 1
+		}
+		state, err := loadState(cfg)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		collector := tfwatch.NewCollector(tfwatch.CollectorConfig{
+			Directory:    cfg.Directory,
+			Phase:        cfg.Phase,
+			OTELEndpoint: otelTarget(cfg),
+			Versions:     cfg.versions,
+			Concurrency:  cfg.Concurrency,
+			State:        state,
+		})
+		summary = collector.CollectRoots(ctx, cfg.Directory, roots, opts)
+		retireMissingRoots(ctx, cfg, collector, roots)
+		err = tel.Flush(ctx)
+		_ = tel.Shutdown(ctx)
+		if err != nil {
+			log.Print(err)
+			undelivered = true
+		} else {
+			if err := saveState(cfg, state); err != nil {
+				log.Print(err)
+				return 1
+			}
+			fmt.Println("\nDone. Metrics published to", otelTarget(cfg))
+		}
+	}
+
+	summary.Print()
+	if len(summary.Failed) > 0 {
+		return 1
+	}
+	return deliveryCode(0, undelivered)
+}
+
+// runStructured scans like the text mode does (optionally publishing metrics)
+// but writes a machine-readable report to stdout instead of the human output.
+// It returns the process exit code.
+func runStructured(cfg Config) int {
+	ctx := context.Background()
+	results, err := scanAll(ctx, cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if cfg.versions != nil {
+		tfwatch.CheckOutdated(ctx, cfg.versions, results, cfg.Concurrency)
+	}
+
+	undelivered := false
+	if !cfg.ListOnly {
+		if err := publishResults(ctx, cfg, results); errors.Is(err, errUndelivered) {
+			log.Print(err)
+			undelivered = true
+		} else if err != nil {
+			log.Print(err)
+			return 1
+		}
+	}
+
+	if err := writeOutput(os.Stdout, cfg, results); err != nil {
+		log.Printf("Failed to write %s output: %v", cfg.Output, err)
+		return 1
+	}
+
+	return deliveryCode(exitCode(results), undelivered)
+}
+
+// runOutdated scans like runStructured, then looks up the latest registry
+// version of every module and provider (or reads it from --version-index).
+// The comparison is printed as a table (or written in the --output format)
+// and, unless --list is set, published alongside the usual dependency
+// metrics. It returns the process exit code.
+func runOutdated(cfg Config) int {
+	ctx := context.Background()
+	results, err := scanAll(ctx, cfg)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	versions := cfg.versions
+	if versions == nil {
+		versions = tfwatch.NewRegistryClient(cfg.RegistryTimeout)
+	}
+	tfwatch.CheckOutdated(ctx, versions, results, cfg.Concurrency)
+
+	if cfg.Output == "text" {
+		printBanner()
+		tfwatch.PrintOutdated(cfg.Directory, results)
+	}
+
+	undelivered := false
+	if !cfg.ListOnly {
+		if err := publishResults(ctx, cfg, results); errors.Is(err, errUndelivered) {
+			log.Print(err)
+			undelivered = true
+		} else if err != nil {
+			log.Print(err)
+			return 1
+		} else if cfg.Output == "text" {
+			fmt.Println("\nDone. Metrics published to", otelTarget(cfg))
+		}
+	}
+
+	if cfg.Output != "text" {
+		if err := writeOutput(os.Stdout, cfg, results); err != nil {
+			log.Printf("Failed to write %s output: %v", cfg.Output, err)
+			return 1
+		}
+	}
+
+	code := exitCode(results)
+	for _, msg := range tfwatch.OutdatedErrors(cfg.Directory, results) {
+		log.Printf("Error: %s", msg)
+		code = 1
+	}
+	return deliveryCode(code, undelivered)
+}
+
+// runCheck scans like runStructured and evaluates the --policy rules against
+// every root. For platforms rules, the checksums of the locked providers are
+// first looked up on their registries. Violations are printed (or written in
+// the --output format) and, unless --list is set, published as
+// terraform_policy_violation. It returns exitCheckFailed if a root could not
+// be scanned or a checksum could not be looked up, exitViolations if any
+// violation is at least as severe as --fail-on, and 0 otherwise.
+func runCheck(cfg Config) int {
+	policy, err := tfwatch.LoadPolicy(cfg.Policy)
+	if err != nil {
+		log.Print(err)
+		return exitCheckFailed
+	}
+
+	ctx := context.Background()
+	results, err := scanAll(ctx, cfg)
+	if err != nil {
+		log.Print(err)
+		return exitCheckFailed
+	}
+	platforms := policy.Platforms()
+	if len(platforms) > 0 {
+		tfwatch.CheckPlatforms(ctx, tfwatch.NewRegistryClient(cfg.RegistryTimeout), results, platforms, cfg.Concurrency)
+	}
+	tfwatch.CheckPolicy(policy, results, "")
+
+	if cfg.Output == "text" {
+		printBanner()
+		if len(platforms) > 0 {
+			tfwatch.PrintPlatforms(cfg.Directory, results)
+		}
+		tfwatch.PrintViolations(cfg.Directory, results)
+	}
+
+	undelivered := false
+	if !cfg.ListOnly {
+		if err := publishResults(ctx, cfg, results); errors.Is(err, errUndelivered) {
+			log.Print(err)
+			undelivered = true
+		} else if err != nil {
+			log.Print(err)
+			return exitCheckFailed
+		} else if cfg.Output == "text" {
+			fmt.Println("\nDone. Metrics published to", otelTarget(cfg))
+		}
+	}
+
+	if cfg.Output != "text" {
+		if err := writeOutput(os.Stdout, cfg, results); err != nil {
+			log.Printf("Failed to write %s output: %v", cfg.Output, err)
+			return exitCheckFailed
+		}
+	}
+
+	if exitCode(results) != 0 {
+		return exitCheckFailed
+	}
+	if n := tfwatch.PlatformErrors(results); n > 0 {
+		log.Printf("Error: the checksums of %d provider(s) could not be looked up", n)
+		return exitCheckFailed
+	}
+	summary := tfwatch.Summarize(results)
+	if summary.Errors > 0 || (cfg.FailOn == tfwatch.SeverityWarning && summary.Warnings > 0) {
+		return exitViolations
+	}
+	return deliveryCode(0, undelivered)
+}
+
+// Exit codes of "tfwatch check" and "tfwatch verify", and exitUndelivered of
+// every one-shot command.
+const (
+	exitCheckFailed = 1 // the policy could not be evaluated, or the providers verified, for every root
+	exitViolations  = 2 // violations at or above --fail-on, or unverified provider packages, were found
+	exitUndelivered = 3 // the run succeeded but its metrics were not delivered
+)
+
+// deliveryCode returns exitUndelivered if the run otherwise succeeded with
+// code 0 but its metrics were not delivered, and code otherwise. Scan
+// failures and policy violations take precedence.
+func deliveryCode(code int, undelivered bool) int {
+	if code == 0 && undelivered {
+		return exitUndelivered
+	}
+	return code
+}
+
+// scanAll scans --dir, or every root module under it with --recursive.
+func scanAll(ctx context.Context, cfg Config) ([]tfwatch.ScanResult, error) {
+	dirs := []string{cfg.Directory}
+	if cfg.Recursive {
+		var err error
+		if dirs, err = discoverRoots(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return tfwatch.ScanRoots(ctx, dirs, scanOptions(cfg)), nil
+}
+
+// publishResults publishes scan results without the human-readable output,
+// labelling each root with its directory when scanning recursively.
+func publishResults(ctx context.Context, cfg Config, results []tfwatch.ScanResult) error {
+	tel, err := initOTEL(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize OTEL: %w", err)
+	}
+	defer func() { _ = tel.Shutdown(ctx) }()
+
+	state, err := loadState(cfg)
+	if err != nil {
+		return err
+	}
+	collector := tfwatch.NewCollector(tfwatch.CollectorConfig{
+		Directory:    cfg.Directory,
+		Phase:        cfg.Phase,
+		OTELEndpoint: otelTarget(cfg),
+		Quiet:        true,
+		State:        state,
+	})
+	if cfg.Recursive {
+		collector.PublishRoots(ctx, cfg.Directory, results)
+		dirs := make([]string, len(results))
+		for i, res := range results {
+			dirs[i] = res.Directory
+		}
+		retireMissingRoots(ctx, cfg, collector, dirs)
+	} else if err := collector.Publish(ctx, results[0]); err != nil {
+		log.Printf("Failed to collect dependencies: %v", err)
+	}
+	// The state only moves on once the collector has the metrics, including
+	// the zeros for retired series; otherwise the next run retires them again.
+	if err := tel.Flush(ctx); err != nil {
+		return err
+	}
+	return saveState(cfg, state)
+}
+
+// retireMissingRoots retires the series of roots that were published before
+// but are no longer discovered. With --include or --exclude the discovery is
+// partial, so nothing is retired.
+func retireMissingRoots(ctx context.Context, cfg Config, collector *tfwatch.Collector, dirs []string) {
+	if len(cfg.Include) == 0 && len(cfg.Exclude) == 0 {
+		collector.RetireMissingRoots(ctx, cfg.Directory, dirs)
+	}
+}
+
+// loadState reads --state-file, or returns nil if it is not set.
+func loadState(cfg Config) (*tfwatch.SeriesState, error) {
+	if cfg.StateFile == "" {
+		return nil, nil
+	}
+	return tfwatch.LoadSeriesState(cfg.StateFile)
+}
+
+// saveState writes state back to --state-file; it does nothing if state is nil.
+func saveState(cfg Config, state *tfwatch.SeriesState) error {
+	if state == nil {
+		return nil
+	}
+	return state.Save(cfg.StateFile)
+}
+
+// exitCode returns 1 if any root failed to scan, 0 otherwise.
+func exitCode(results []tfwatch.ScanResult) int {
+	for _, res := range results {
+		if res.Err != nil {
+			return 1
+		}
+	}
+	return 0
+}
+
+// writeOutput encodes scan results in the structured format selected by --output.
+func writeOutput(w io.Writer, cfg Config, results []tfwatch.ScanResult) error {
+	opts := tfwatch.ReportOptions{
+		ToolVersion: version,
+		Phase:       cfg.Phase,
+	}
+	switch cfg.Output {
+	case "cyclonedx":
+		return tfwatch.NewCycloneDXBOM(cfg.Directory, results, opts).Write(w)
+	case "spdx":
+		return tfwatch.NewSPDXDocument(cfg.Directory, results, opts).Write(w)
+	default:
+		return tfwatch.NewReport(cfg.Directory, results, opts).Write(w, cfg.Output)
+	}
 }
 
 func parseFlags() Config {
@@ -85,26 +587,236 @@ func parseFlags() Config {
 
 // parseFlagsFrom parses flags from the given args. Returns (config, exitCode).
 // exitCode < 0 means continue; >= 0 means the caller should exit with that code.
+//
+// A leading command name selects the command: "scan" (the default),
+// "outdated", "check", "verify", "serve", "watch", "flush-spool" or
+// "publish". After a command name the directory may also be given as a
+// positional argument.
 func parseFlagsFrom(args []string) (Config, int) {
-	var cfg Config
-	fs := flag.NewFlagSet("tfwatch", flag.ContinueOnError)
+	cfg := Config{Command: "scan", OTELHeaders: map[string]string{}, PushgatewayHeaders: map[string]string{}}
+	name := "tfwatch"
+	subcommand := len(args) > 0 && slices.Contains(commands, args[0])
+	if subcommand {
+		cfg.Command = args[0]
+		name = "tfwatch " + args[0]
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 
 	fs.StringVar(&cfg.Directory, "dir", ".", "Terraform configuration directory (default: current directory)")
 	fs.StringVar(&cfg.Phase, "phase", "plan", "Terraform phase: plan or apply")
-	fs.StringVar(&cfg.OTELEndpoint, "otel-endpoint", "localhost:4317", "OTEL collector endpoint")
-	fs.BoolVar(&cfg.OTELInsecure, "otel-insecure", true, "Use insecure gRPC connection")
+	fs.StringVar(&cfg.OTELEndpoint, "otel-endpoint", "localhost:4317", "OTEL collector endpoint (default localhost:4318 for the HTTP protocols)")
+	fs.BoolVar(&cfg.OTELInsecure, "otel-insecure", true, "Use an insecure connection (plaintext gRPC or http://)")
+	fs.StringVar(&cfg.OTELProtocol, "otel-protocol", tfwatch.ProtocolGRPC, "OTLP protocol: grpc, http/protobuf or http/json")
+	fs.StringVar(&cfg.OTELPath, "otel-path", tfwatch.DefaultURLPath, "URL path for the HTTP protocols")
+	fs.StringVar(&cfg.OTELCompression, "otel-compression", "none", "OTLP compression: gzip or none")
+	fs.Var(headerFlag(cfg.OTELHeaders), "otel-header", "Header sent with every export, as key=value (repeatable), e.g. an API key")
+	fs.DurationVar(&cfg.OTELTimeout, "otel-timeout", 10*time.Second, "Timeout for each export attempt")
+	fs.DurationVar(&cfg.OTELDeadline, "otel-deadline", 30*time.Second, "How long to retry a failed export before giving up (0 disables retries)")
+	if cfg.Command != "publish" {
+		fs.StringVar(&cfg.OTELFile, "otel-file", "", "Append the OTLP/JSON payloads to this file instead of pushing them (see tfwatch publish)")
+	}
+	fs.StringVar(&cfg.Pushgateway, "pushgateway", "", "Push to this Prometheus Pushgateway URL instead of an OTEL collector, grouped by backend_org and backend_workspace")
+	fs.StringVar(&cfg.PushgatewayJob, "pushgateway-job", tfwatch.DefaultPushgatewayJob, "Job label of the metrics pushed with --pushgateway")
+	fs.Var(headerFlag(cfg.PushgatewayHeaders), "pushgateway-header", "Header sent with every push to --pushgateway, as key=value (repeatable), e.g. Authorization")
+	fs.StringVar(&cfg.PromFile, "prom-file", "", "Write the metrics to this .prom file for the node_exporter textfile collector instead of pushing them")
+	fs.StringVar(&cfg.OTELCAFile, "otel-ca-file", "", "PEM CA bundle to verify the collector's certificate (default: system roots)")
+	fs.StringVar(&cfg.OTELClientCert, "otel-client-cert", "", "PEM client certificate for mutual TLS (requires --otel-client-key)")
+	fs.StringVar(&cfg.OTELClientKey, "otel-client-key", "", "PEM private key for --otel-client-cert")
+	fs.StringVar(&cfg.OTELServerName, "otel-server-name", "", "Name to verify the collector's certificate against (default: the endpoint host)")
 	fs.BoolVar(&cfg.ListOnly, "list", false, "List modules and providers without publishing metrics")
+	fs.BoolVar(&cfg.Recursive, "recursive", false, "Scan every Terraform root module under --dir")
+	fs.Var((*stringList)(&cfg.Include), "include", "Only scan root modules whose relative path matches this glob (repeatable, requires --recursive)")
+	fs.Var((*stringList)(&cfg.Exclude), "exclude", "Skip root modules whose relative path matches this glob (repeatable, requires --recursive)")
+	fs.IntVar(&cfg.Concurrency, "concurrency", 4, "Number of root modules scanned in parallel (with --recursive)")
+	fs.IntVar(&cfg.InitConcurrency, "init-concurrency", 1, "Maximum concurrent terraform init runs (with --recursive)")
+	fs.BoolVar(&cfg.NoInit, "no-init", false, "Never run terraform init; read modules and their version constraints from the module blocks instead")
+	fs.StringVar(&cfg.Output, "output", "text", "Output format: text, json, yaml, cyclonedx or spdx")
+	fs.StringVar(&cfg.VersionIndex, "version-index", "", "YAML or JSON file of available versions per source; enables version-lag metrics without registry access")
+	switch cfg.Command {
+	case "scan", "outdated", "check", "flush-spool":
+		fs.StringVar(&cfg.SpoolDir, "spool-dir", "", "Directory where metrics that cannot be delivered are kept; they are replayed at the start of the next run")
+	}
+	if cfg.Command != "serve" {
+		fs.StringVar(&cfg.StateFile, "state-file", "", "File remembering the series published per root; series that disappear are published as 0 on the next run")
+	}
+	if cfg.Command == "outdated" || cfg.Command == "check" {
+		fs.DurationVar(&cfg.RegistryTimeout, "registry-timeout", 30*time.Second, "Timeout for each registry request")
+	}
+	switch cfg.Command {
+	case "check":
+		fs.StringVar(&cfg.Policy, "policy", "", "Policy file (YAML) to evaluate")
+		fs.StringVar(&cfg.FailOn, "fail-on", tfwatch.SeverityError, "Lowest violation severity that fails the check: error or warning")
+	case "serve":
+		fs.StringVar(&cfg.Listen, "listen", ":9464", "Address to serve Prometheus /metrics on")
+		fs.DurationVar(&cfg.Interval, "interval", 10*time.Minute, "Time between scans")
+		fs.BoolVar(&cfg.OTELPush, "otel-push", false, "Also push every scan to --otel-endpoint via OTLP")
+	case "watch":
+		fs.DurationVar(&cfg.Debounce, "debounce", tfwatch.DefaultDebounce, "Quiet period after a change before the root is rescanned")
+	case "publish":
+		fs.StringVar(&cfg.PublishFrom, "from", "", "File written with --otel-file to push")
+	}
 	showVersion := fs.Bool("version", false, "Show version")
 
 	if err := fs.Parse(args); err != nil {
 		return cfg, 1
 	}
+	if subcommand && fs.NArg() > 0 {
+		// Allow flags after the positional directory, e.g. "scan ./infra --recursive".
+		cfg.Directory = fs.Arg(0)
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return cfg, 1
+		}
+	}
 
 	if *showVersion {
 		fmt.Printf("tfwatch %s\n", version)
 		return cfg, 0
 	}
 
+	if err := applyOTELEnv(&cfg, fs, os.Getenv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return cfg, 1
+	}
+
+	switch cfg.OTELProtocol {
+	case tfwatch.ProtocolGRPC, tfwatch.ProtocolHTTPProtobuf, tfwatch.ProtocolHTTPJSON:
+	default:
+		fmt.Fprintln(os.Stderr, "Error: --otel-protocol must be 'grpc', 'http/protobuf' or 'http/json'")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.OTELTimeout <= 0 || cfg.OTELDeadline < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --otel-timeout must be positive and --otel-deadline must not be negative")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if (cfg.OTELClientCert == "") != (cfg.OTELClientKey == "") {
+		fmt.Fprintln(os.Stderr, "Error: --otel-client-cert and --otel-client-key must be given together")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.OTELInsecure && (cfg.OTELCAFile != "" || cfg.OTELClientCert != "" || cfg.OTELServerName != "") {
+		fmt.Fprintln(os.Stderr, "Error: --otel-ca-file, --otel-client-cert and --otel-server-name require --otel-insecure=false")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	sinks := 0
+	for _, s := range []string{cfg.OTELFile, cfg.Pushgateway, cfg.PromFile} {
+		if s != "" {
+			sinks++
+		}
+	}
+	if sinks > 1 {
+		fmt.Fprintln(os.Stderr, "Error: --otel-file, --pushgateway and --prom-file are mutually exclusive")
+		fs.Usage()
+		return cfg, 1
+	}
+	// OTLP headers usually carry a vendor API key, which must not leak to a
+	// Pushgateway that may well be plain HTTP.
+	if cfg.Pushgateway != "" && flagSet(fs, "otel-header") {
+		fmt.Fprintln(os.Stderr, "Error: --otel-header is not sent to the Pushgateway; use --pushgateway-header")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.OTELCompression != "gzip" && cfg.OTELCompression != "none" {
+		fmt.Fprintln(os.Stderr, "Error: --otel-compression must be 'gzip' or 'none'")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Error: unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if !cfg.Recursive && (len(cfg.Include) > 0 || len(cfg.Exclude) > 0) {
+		fmt.Fprintln(os.Stderr, "Error: --include and --exclude require --recursive")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Concurrency < 1 || cfg.InitConcurrency < 1 {
+		fmt.Fprintln(os.Stderr, "Error: --concurrency and --init-concurrency must be at least 1")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	switch cfg.Output {
+	case "text", "json", "yaml", "cyclonedx", "spdx":
+	default:
+		fmt.Fprintln(os.Stderr, "Error: --output must be 'text', 'json', 'yaml', 'cyclonedx' or 'spdx'")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "serve" && (cfg.Output != "text" || cfg.ListOnly) {
+		fmt.Fprintln(os.Stderr, "Error: tfwatch serve does not support --output or --list")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "verify" && cfg.Output != "text" {
+		fmt.Fprintln(os.Stderr, "Error: tfwatch verify does not support --output")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "watch" && cfg.Output != "text" {
+		fmt.Fprintln(os.Stderr, "Error: tfwatch watch does not support --output")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "watch" && cfg.Debounce <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --debounce must be positive")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "publish" && cfg.PublishFrom == "" {
+		fmt.Fprintln(os.Stderr, "Error: tfwatch publish requires --from")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "flush-spool" && cfg.SpoolDir == "" {
+		fmt.Fprintln(os.Stderr, "Error: tfwatch flush-spool requires --spool-dir")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "serve" && cfg.Interval < time.Second {
+		fmt.Fprintln(os.Stderr, "Error: --interval must be at least 1s")
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command != "scan" && (cfg.Output == "cyclonedx" || cfg.Output == "spdx") {
+		fmt.Fprintf(os.Stderr, "Error: tfwatch %s supports --output text, json or yaml\n", cfg.Command)
+		fs.Usage()
+		return cfg, 1
+	}
+
+	if cfg.Command == "check" {
+		if cfg.Policy == "" {
+			fmt.Fprintln(os.Stderr, "Error: tfwatch check requires --policy")
+			fs.Usage()
+			return cfg, 1
+		}
+		if cfg.FailOn != tfwatch.SeverityError && cfg.FailOn != tfwatch.SeverityWarning {
+			fmt.Fprintln(os.Stderr, "Error: --fail-on must be 'error' or 'warning'")
+			fs.Usage()
+			return cfg, 1
+		}
+	}
+
 	if cfg.Phase != "plan" && cfg.Phase != "apply" {
 		fmt.Fprintln(os.Stderr, "Error: --phase must be 'plan' or 'apply'")
 		fs.Usage()
@@ -114,39 +826,130 @@ func parseFlagsFrom(args []string) (Config, int) {
 	return cfg, -1
 }
 
-func initOTEL(ctx context.Context, cfg Config) (func(context.Context) error, error) {
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName("tfwatch"),
-			semconv.ServiceVersion(version),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
-	}
+// telemetry is the OTEL pipeline of a one-shot run. Its MeterProvider is
+// installed globally; everything recorded on it is exported by Flush.
+type telemetry struct {
+	endpoint string
+	spoolDir string
+	provider *sdkmetric.MeterProvider
+	reader   *sdkmetric.ManualReader
+	exporter sdkmetric.Exporter
+}
 
-	opts := []otlpmetricgrpc.Option{
-		otlpmetricgrpc.WithEndpoint(cfg.OTELEndpoint),
-	}
+// errUndelivered reports that metrics were recorded but never reached the
+// collector.
+var errUndelivered = errors.New("metrics were not delivered")
 
-	if cfg.OTELInsecure {
-		opts = append(opts, otlpmetricgrpc.WithInsecure())
-	} else {
-		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, "")))
+func initOTEL(ctx context.Context, cfg Config) (*telemetry, error) {
+	res, err := newResource(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	exporter, err := newExporter(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create exporter: %w", err)
+		return nil, err
 	}
 
+	// A manual reader rather than a periodic one: the export happens
+	// exactly once, when Flush is called, and its error is not lost in a
+	// deferred Shutdown.
+	reader := sdkmetric.NewManualReader()
 	meterProvider := sdkmetric.NewMeterProvider(
 		sdkmetric.WithResource(res),
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithReader(reader),
 	)
 	otel.SetMeterProvider(meterProvider)
 
-	return meterProvider.Shutdown, nil
+	// Deliver what earlier runs could not before this run's own metrics,
+	// so that the collector receives them in order.
+	if cfg.SpoolDir != "" {
+		flushSpool(ctx, cfg, exporter)
+	}
+
+	return &telemetry{endpoint: otelTarget(cfg), spoolDir: cfg.SpoolDir, provider: meterProvider, reader: reader, exporter: exporter}, nil
+}
+
+// Flush exports everything recorded so far. Failed exports are retried with
+// backoff for up to --otel-deadline; an error wrapping errUndelivered means
+// the collector did not accept the metrics in that time. With --spool-dir
+// the metrics are then kept there for a later run.
+func (t *telemetry) Flush(ctx context.Context) error {
+	rm := &metricdata.ResourceMetrics{}
+	if err := t.reader.Collect(ctx, rm); err != nil {
+		return fmt.Errorf("failed to collect metrics: %w", err)
+	}
+	err := t.exporter.Export(ctx, rm)
+	if err == nil {
+		return nil
+	}
+	if t.spoolDir != "" {
+		if path, serr := tfwatch.SpoolMetrics(t.spoolDir, rm); serr != nil {
+			log.Printf("Failed to spool undelivered metrics: %v", serr)
+		} else {
+			log.Printf("Spooled undelivered metrics to %s", path)
+		}
+	}
+	return fmt.Errorf("%w to %s: %v", errUndelivered, t.endpoint, err)
+}
+
+// Shutdown releases the provider and the exporter's connection.
+func (t *telemetry) Shutdown(ctx context.Context) error {
+	return errors.Join(t.provider.Shutdown(ctx), t.exporter.Shutdown(ctx))
+}
+
+// newResource describes tfwatch itself in exported metrics.
+func newResource(ctx context.Context) (*resource.Resource, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName("tfwatch"),
+			semconv.ServiceVersion(version),
+		),
+		// OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME override the above.
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+	return res, nil
+}
+
+// newExporter creates the OTLP exporter for cfg.OTELEndpoint, or the sink
+// selected by --otel-file, --pushgateway or --prom-file.
+func newExporter(ctx context.Context, cfg Config) (sdkmetric.Exporter, error) {
+	switch {
+	case cfg.OTELFile != "":
+		return tfwatch.NewFileExporter(cfg.OTELFile)
+	case cfg.Pushgateway != "":
+		return tfwatch.NewPushgatewayExporter(tfwatch.PushgatewayConfig{
+			URL:           cfg.Pushgateway,
+			Job:           cfg.PushgatewayJob,
+			Headers:       cfg.PushgatewayHeaders,
+			Timeout:       cfg.OTELTimeout,
+			RetryDeadline: cfg.OTELDeadline,
+		})
+	case cfg.PromFile != "":
+		return tfwatch.NewTextfileExporter(cfg.PromFile)
+	}
+	return tfwatch.NewExporter(ctx, tfwatch.ExporterConfig{
+		Protocol:      cfg.OTELProtocol,
+		Endpoint:      cfg.OTELEndpoint,
+		Insecure:      cfg.OTELInsecure,
+		URLPath:       cfg.OTELPath,
+		Compression:   cfg.OTELCompression,
+		Headers:       cfg.OTELHeaders,
+		Timeout:       cfg.OTELTimeout,
+		RetryDeadline: cfg.OTELDeadline,
+		CAFile:        cfg.OTELCAFile,
+		ClientCert:    cfg.OTELClientCert,
+		ClientKey:     cfg.OTELClientKey,
+		ServerName:    cfg.OTELServerName,
+	})
+}
+
+// otelTarget names where metrics go, for messages.
+func otelTarget(cfg Config) string {
+	return cmp.Or(cfg.OTELFile, cfg.Pushgateway, cfg.PromFile, cfg.OTELEndpoint)
 }
 
 func printBanner() {
@@ -3,10 +3,19 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
 )
 
 func captureStdout(fn func()) string {
@@ -36,6 +45,9 @@ func TestParseFlagsFrom(t *testing.T) {
 			wantExit: -1,
 			checks: func(t *testing.T, cfg Config) {
 				t.Helper()
+				if cfg.Command != "scan" {
+					t.Errorf("expected command 'scan', got %q", cfg.Command)
+				}
 				if cfg.Directory != "." {
 					t.Errorf("expected dir '.', got %q", cfg.Directory)
 				}
@@ -48,9 +60,15 @@ func TestParseFlagsFrom(t *testing.T) {
 				if !cfg.OTELInsecure {
 					t.Error("expected otel-insecure=true")
 				}
+				if cfg.OTELProtocol != "grpc" || cfg.OTELCompression != "none" {
+					t.Errorf("expected grpc without compression, got %q/%q", cfg.OTELProtocol, cfg.OTELCompression)
+				}
 				if cfg.ListOnly {
 					t.Error("expected list=false")
 				}
+				if cfg.Output != "text" {
+					t.Errorf("expected output 'text', got %q", cfg.Output)
+				}
 			},
 		},
 		{
@@ -82,6 +100,384 @@ func TestParseFlagsFrom(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:     "scan with positional dir and trailing flags",
+			args:     []string{"scan", "./infra", "--recursive", "--include", "prod/**", "--exclude", "legacy/**", "--exclude", "sandbox"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Directory != "./infra" {
+					t.Errorf("expected dir './infra', got %q", cfg.Directory)
+				}
+				if !cfg.Recursive {
+					t.Error("expected recursive=true")
+				}
+				if len(cfg.Include) != 1 || cfg.Include[0] != "prod/**" {
+					t.Errorf("unexpected include: %v", cfg.Include)
+				}
+				if len(cfg.Exclude) != 2 || cfg.Exclude[1] != "sandbox" {
+					t.Errorf("unexpected exclude: %v", cfg.Exclude)
+				}
+			},
+		},
+		{
+			name:     "concurrency",
+			args:     []string{"--recursive", "--concurrency", "16", "--init-concurrency", "2"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Concurrency != 16 || cfg.InitConcurrency != 2 {
+					t.Errorf("expected concurrency 16/2, got %d/%d", cfg.Concurrency, cfg.InitConcurrency)
+				}
+			},
+		},
+		{
+			name:     "invalid concurrency",
+			args:     []string{"--concurrency", "0"},
+			wantExit: 1,
+		},
+		{
+			name:     "include without recursive",
+			args:     []string{"--include", "prod/**"},
+			wantExit: 1,
+		},
+		{
+			name:     "positional dir without scan",
+			args:     []string{"./infra"},
+			wantExit: 1,
+		},
+		{
+			name:     "json output",
+			args:     []string{"--output", "json"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Output != "json" {
+					t.Errorf("expected output json, got %q", cfg.Output)
+				}
+			},
+		},
+		{
+			name:     "cyclonedx output",
+			args:     []string{"--list", "--output", "cyclonedx"},
+			wantExit: -1,
+		},
+		{
+			name:     "spdx output",
+			args:     []string{"--output", "spdx"},
+			wantExit: -1,
+		},
+		{
+			name:     "outdated command",
+			args:     []string{"outdated", "./infra", "--list", "--registry-timeout", "5s"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Command != "outdated" {
+					t.Errorf("expected command 'outdated', got %q", cfg.Command)
+				}
+				if cfg.Directory != "./infra" {
+					t.Errorf("expected dir './infra', got %q", cfg.Directory)
+				}
+				if cfg.RegistryTimeout != 5*time.Second {
+					t.Errorf("expected registry timeout 5s, got %v", cfg.RegistryTimeout)
+				}
+			},
+		},
+		{
+			name:     "version index on scan",
+			args:     []string{"--version-index", "versions.yaml"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.VersionIndex != "versions.yaml" {
+					t.Errorf("expected version index 'versions.yaml', got %q", cfg.VersionIndex)
+				}
+			},
+		},
+		{
+			name:     "registry timeout requires outdated or check",
+			args:     []string{"--registry-timeout", "5s"},
+			wantExit: 1,
+		},
+		{
+			name:     "outdated rejects sbom output",
+			args:     []string{"outdated", "--output", "cyclonedx"},
+			wantExit: 1,
+		},
+		{
+			name:     "check command",
+			args:     []string{"check", "./infra", "--policy", "policy.yaml", "--fail-on", "warning", "--registry-timeout", "5s"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Command != "check" || cfg.Policy != "policy.yaml" || cfg.FailOn != "warning" || cfg.RegistryTimeout != 5*time.Second {
+					t.Errorf("unexpected check config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "check requires policy",
+			args:     []string{"check"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid fail-on",
+			args:     []string{"check", "--policy", "policy.yaml", "--fail-on", "info"},
+			wantExit: 1,
+		},
+		{
+			name:     "verify command",
+			args:     []string{"verify", "./infra", "--recursive"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Command != "verify" || cfg.Directory != "./infra" || !cfg.Recursive {
+					t.Errorf("unexpected verify config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "verify rejects json output",
+			args:     []string{"verify", "--output", "json"},
+			wantExit: 1,
+		},
+		{
+			name:     "serve command",
+			args:     []string{"serve", "./infra", "--recursive", "--interval", "5m", "--listen", "127.0.0.1:9000", "--otel-push"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Command != "serve" || cfg.Directory != "./infra" || !cfg.Recursive {
+					t.Errorf("unexpected serve config: %+v", cfg)
+				}
+				if cfg.Interval != 5*time.Minute || cfg.Listen != "127.0.0.1:9000" || !cfg.OTELPush {
+					t.Errorf("unexpected serve config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "serve defaults",
+			args:     []string{"serve"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Interval != 10*time.Minute || cfg.Listen != ":9464" || cfg.OTELPush {
+					t.Errorf("unexpected serve defaults: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "serve rejects output",
+			args:     []string{"serve", "--output", "json"},
+			wantExit: 1,
+		},
+		{
+			name:     "serve interval too short",
+			args:     []string{"serve", "--interval", "10ms"},
+			wantExit: 1,
+		},
+		{
+			name:     "interval requires serve",
+			args:     []string{"--interval", "5m"},
+			wantExit: 1,
+		},
+		{
+			name:     "watch command",
+			args:     []string{"watch", "./infra", "--recursive", "--debounce", "500ms", "--list"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Command != "watch" || cfg.Directory != "./infra" || !cfg.Recursive || !cfg.ListOnly {
+					t.Errorf("unexpected watch config: %+v", cfg)
+				}
+				if cfg.Debounce != 500*time.Millisecond {
+					t.Errorf("expected debounce 500ms, got %s", cfg.Debounce)
+				}
+			},
+		},
+		{
+			name:     "watch rejects output",
+			args:     []string{"watch", "--output", "yaml"},
+			wantExit: 1,
+		},
+		{
+			name:     "watch invalid debounce",
+			args:     []string{"watch", "--debounce", "0s"},
+			wantExit: 1,
+		},
+		{
+			name:     "state file",
+			args:     []string{"scan", "--recursive", "--state-file", "tfwatch-state.json"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.StateFile != "tfwatch-state.json" {
+					t.Errorf("expected state file, got %q", cfg.StateFile)
+				}
+			},
+		},
+		{
+			name:     "serve rejects state file",
+			args:     []string{"serve", "--state-file", "tfwatch-state.json"},
+			wantExit: 1,
+		},
+		{
+			name:     "http protocol defaults to port 4318",
+			args:     []string{"--otel-protocol", "http/protobuf", "--otel-compression", "gzip", "--otel-path", "/otlp/v1/metrics"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELEndpoint != "localhost:4318" {
+					t.Errorf("expected endpoint localhost:4318, got %q", cfg.OTELEndpoint)
+				}
+				if cfg.OTELCompression != "gzip" || cfg.OTELPath != "/otlp/v1/metrics" {
+					t.Errorf("unexpected OTLP config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "http protocol keeps explicit endpoint",
+			args:     []string{"--otel-protocol", "http/json", "--otel-endpoint", "otel.example.com:443"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELEndpoint != "otel.example.com:443" {
+					t.Errorf("expected explicit endpoint, got %q", cfg.OTELEndpoint)
+				}
+			},
+		},
+		{
+			name:     "invalid protocol",
+			args:     []string{"--otel-protocol", "http"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid compression",
+			args:     []string{"--otel-compression", "zstd"},
+			wantExit: 1,
+		},
+		{
+			name:     "otel deadline",
+			args:     []string{"--otel-deadline", "2m"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELDeadline != 2*time.Minute {
+					t.Errorf("expected deadline 2m, got %s", cfg.OTELDeadline)
+				}
+			},
+		},
+		{
+			name:     "flush-spool",
+			args:     []string{"flush-spool", "--spool-dir", "/var/spool/tfwatch"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Command != "flush-spool" || cfg.SpoolDir != "/var/spool/tfwatch" {
+					t.Errorf("unexpected config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "flush-spool requires spool dir",
+			args:     []string{"flush-spool"},
+			wantExit: 1,
+		},
+		{
+			name:     "otel file",
+			args:     []string{"--otel-file", "metrics.jsonl"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELFile != "metrics.jsonl" {
+					t.Errorf("expected otel file metrics.jsonl, got %q", cfg.OTELFile)
+				}
+			},
+		},
+		{
+			name:     "pushgateway",
+			args:     []string{"--pushgateway", "http://pushgateway:9091", "--pushgateway-job", "terraform", "--pushgateway-header", "Authorization=Basic dGY6d2F0Y2g="},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Pushgateway != "http://pushgateway:9091" || cfg.PushgatewayJob != "terraform" ||
+					cfg.PushgatewayHeaders["Authorization"] != "Basic dGY6d2F0Y2g=" {
+					t.Errorf("unexpected config: %+v", cfg)
+				}
+				if got := otelTarget(cfg); got != "http://pushgateway:9091" {
+					t.Errorf("expected the Pushgateway as target, got %s", got)
+				}
+			},
+		},
+		{
+			name:     "pushgateway rejects otel headers",
+			args:     []string{"--pushgateway", "http://pushgateway:9091", "--otel-header", "api-key=secret"},
+			wantExit: 1,
+		},
+		{
+			name:     "prom file",
+			args:     []string{"--prom-file", "tfwatch.prom"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.PromFile != "tfwatch.prom" || cfg.PushgatewayJob != "tfwatch" {
+					t.Errorf("unexpected config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "sinks are mutually exclusive",
+			args:     []string{"--prom-file", "tfwatch.prom", "--pushgateway", "http://pushgateway:9091"},
+			wantExit: 1,
+		},
+		{
+			name:     "no init",
+			args:     []string{"scan", "--no-init", "--recursive", "./infra"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if !cfg.NoInit || !scanOptions(cfg).NoInit {
+					t.Errorf("expected --no-init to reach the scan options: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "publish",
+			args:     []string{"publish", "--from", "metrics.jsonl", "--otel-protocol", "http/json"},
+			wantExit: -1,
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.Command != "publish" || cfg.PublishFrom != "metrics.jsonl" {
+					t.Errorf("unexpected config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name:     "publish requires from",
+			args:     []string{"publish"},
+			wantExit: 1,
+		},
+		{
+			name:     "publish rejects otel file",
+			args:     []string{"publish", "--from", "a.jsonl", "--otel-file", "b.jsonl"},
+			wantExit: 1,
+		},
+		{
+			name:     "serve rejects spool dir",
+			args:     []string{"serve", "--spool-dir", "/var/spool/tfwatch"},
+			wantExit: 1,
+		},
+		{
+			name:     "negative otel deadline",
+			args:     []string{"--otel-deadline", "-1s"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid output",
+			args:     []string{"--output", "xml"},
+			wantExit: 1,
+		},
 		{
 			name:     "version flag",
 			args:     []string{"--version"},
@@ -132,27 +528,123 @@ func TestInitOTEL(t *testing.T) {
 	tests := []struct {
 		name     string
 		insecure bool
+		protocol string
+		endpoint string
 	}{
-		{"insecure", true},
-		{"TLS", false},
+		{"insecure", true, "grpc", "localhost:4317"},
+		{"TLS", false, "grpc", "localhost:4317"},
+		{"http/protobuf", true, "http/protobuf", "localhost:4318"},
+		{"http/json TLS", false, "http/json", "localhost:4318"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cfg := Config{
-				OTELEndpoint: "localhost:4317",
+				OTELEndpoint: tt.endpoint,
 				OTELInsecure: tt.insecure,
+				OTELProtocol: tt.protocol,
+			}
+
+			ctx := context.Background()
+			tel, err := initOTEL(ctx, cfg)
+			if err != nil {
+				t.Fatalf("initOTEL() error: %v", err)
+			}
+			if tel == nil {
+				t.Fatal("expected non-nil telemetry")
 			}
+			tel.Shutdown(ctx)
+		})
+	}
+}
+
+func TestTelemetryFlush(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int // responses in turn; 200 once they run out
+		deadline     time.Duration
+		spool        bool
+		wantRequests int
+		wantErr      bool
+	}{
+		{name: "delivered", wantRequests: 1},
+		{name: "retried", statuses: []int{http.StatusServiceUnavailable}, deadline: 3 * time.Second, wantRequests: 2},
+		{name: "undelivered", statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}, wantRequests: 1, wantErr: true},
+		{name: "spooled", statuses: []int{http.StatusServiceUnavailable}, spool: true, wantRequests: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				requests++
+				if requests <= len(tt.statuses) {
+					http.Error(w, "unavailable", tt.statuses[requests-1])
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
 
 			ctx := context.Background()
-			shutdown, err := initOTEL(ctx, cfg)
+			cfg := Config{
+				OTELEndpoint: strings.TrimPrefix(server.URL, "http://"),
+				OTELInsecure: true,
+				OTELProtocol: "http/protobuf",
+				OTELPath:     "/v1/metrics",
+				OTELTimeout:  time.Second,
+				OTELDeadline: tt.deadline,
+			}
+			if tt.spool {
+				cfg.SpoolDir = t.TempDir()
+			}
+			tel, err := initOTEL(ctx, cfg)
 			if err != nil {
 				t.Fatalf("initOTEL() error: %v", err)
 			}
-			if shutdown == nil {
-				t.Fatal("expected non-nil shutdown function")
+			defer tel.Shutdown(ctx)
+
+			gauge, err := otel.Meter("tfwatch").Int64Gauge("terraform_dependency_version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gauge.Record(ctx, 1)
+
+			err = tel.Flush(ctx)
+			if tt.wantErr != errors.Is(err, errUndelivered) {
+				t.Fatalf("expected undelivered=%v, got %v", tt.wantErr, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if requests != tt.wantRequests {
+				t.Errorf("expected %d request(s), got %d", tt.wantRequests, requests)
+			}
+			if tt.spool {
+				if spooled, _ := filepath.Glob(filepath.Join(cfg.SpoolDir, "*.json")); len(spooled) != 1 {
+					t.Errorf("expected 1 spooled payload, got %v", spooled)
+				}
 			}
-			shutdown(ctx)
 		})
 	}
 }
+
+func TestDeliveryCode(t *testing.T) {
+	tests := []struct {
+		code        int
+		undelivered bool
+		want        int
+	}{
+		{0, false, 0},
+		{0, true, exitUndelivered},
+		{1, true, 1},
+		{exitViolations, true, exitViolations},
+	}
+	for _, tt := range tests {
+		if got := deliveryCode(tt.code, tt.undelivered); got != tt.want {
+			t.Errorf("deliveryCode(%d, %v) = %d, want %d", tt.code, tt.undelivered, got, tt.want)
+		}
+	}
+}
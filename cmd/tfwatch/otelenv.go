@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/tfwatch"
+)
+
+// headerFlag is a repeatable key=value flag collecting OTLP headers.
+type headerFlag map[string]string
+
+func (h headerFlag) String() string {
+	pairs := make([]string, 0, len(h))
+	for k := range h {
+		pairs = append(pairs, k+"=…")
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (h headerFlag) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("header %q must be key=value", v)
+	}
+	h[key] = strings.TrimSpace(value)
+	return nil
+}
+
+// applyOTELEnv fills in exporter settings from the standard OTLP environment
+// variables. A command-line flag always wins; otherwise the metrics-specific
+// OTEL_EXPORTER_OTLP_METRICS_<NAME> wins over OTEL_EXPORTER_OTLP_<NAME>, and
+// both win over the flag defaults. Headers from the environment are merged
+// with --otel-header, which wins for the same key.
+func applyOTELEnv(cfg *Config, fs *flag.FlagSet, getenv func(string) string) error {
+	lookup := func(name string) (value, variable string) {
+		for _, v := range []string{"OTEL_EXPORTER_OTLP_METRICS_" + name, "OTEL_EXPORTER_OTLP_" + name} {
+			if value := strings.TrimSpace(getenv(v)); value != "" {
+				return value, v
+			}
+		}
+		return "", ""
+	}
+
+	if v, _ := lookup("PROTOCOL"); v != "" && !flagSet(fs, "otel-protocol") {
+		cfg.OTELProtocol = v
+	}
+
+	for name, field := range map[string]struct {
+		flag string
+		dst  *string
+	}{
+		"CERTIFICATE":        {"otel-ca-file", &cfg.OTELCAFile},
+		"CLIENT_CERTIFICATE": {"otel-client-cert", &cfg.OTELClientCert},
+		"CLIENT_KEY":         {"otel-client-key", &cfg.OTELClientKey},
+	} {
+		if v, _ := lookup(name); v != "" && !flagSet(fs, field.flag) {
+			*field.dst = v
+		}
+	}
+	// Certificates only make sense over TLS, so they switch off the
+	// --otel-insecure default. An http:// endpoint below still wins.
+	if (cfg.OTELCAFile != "" || cfg.OTELClientCert != "" || cfg.OTELServerName != "") && !flagSet(fs, "otel-insecure") {
+		cfg.OTELInsecure = false
+	}
+
+	if v, variable := lookup("ENDPOINT"); v != "" && !flagSet(fs, "otel-endpoint") {
+		endpoint, insecure, path, err := parseOTLPEndpoint(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", variable, err)
+		}
+		cfg.OTELEndpoint = endpoint
+		if insecure != nil && !flagSet(fs, "otel-insecure") {
+			cfg.OTELInsecure = *insecure
+		}
+		// As in the OTLP spec, the generic endpoint is a base URL that
+		// gets the signal path appended, the metrics endpoint is used as is.
+		if !flagSet(fs, "otel-path") {
+			if variable == "OTEL_EXPORTER_OTLP_ENDPOINT" {
+				cfg.OTELPath = strings.TrimSuffix(path, "/") + tfwatch.DefaultURLPath
+			} else if path != "" {
+				cfg.OTELPath = path
+			}
+		}
+	}
+
+	if v, variable := lookup("HEADERS"); v != "" {
+		headers, err := parseOTLPHeaders(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", variable, err)
+		}
+		for k, val := range headers {
+			if _, ok := cfg.OTELHeaders[k]; !ok {
+				cfg.OTELHeaders[k] = val
+			}
+		}
+	}
+
+	if v, variable := lookup("TIMEOUT"); v != "" && !flagSet(fs, "otel-timeout") {
+		ms, err := strconv.Atoi(v)
+		if err != nil || ms <= 0 {
+			return fmt.Errorf("%s: %q is not a positive number of milliseconds", variable, v)
+		}
+		cfg.OTELTimeout = time.Duration(ms) * time.Millisecond
+	}
+
+	if v, _ := lookup("COMPRESSION"); v != "" && !flagSet(fs, "otel-compression") {
+		cfg.OTELCompression = v
+	}
+
+	// The --otel-endpoint default is the gRPC port; OTLP/HTTP listens on 4318.
+	if v, _ := lookup("ENDPOINT"); v == "" && !flagSet(fs, "otel-endpoint") && cfg.OTELProtocol != tfwatch.ProtocolGRPC {
+		cfg.OTELEndpoint = "localhost:4318"
+	}
+	return nil
+}
+
+// parseOTLPEndpoint splits an endpoint URL such as "https://otlp.example.com"
+// into host:port, whether the scheme is insecure (nil without a scheme) and
+// the URL path. A bare host:port is accepted as well.
+func parseOTLPEndpoint(raw string) (endpoint string, insecure *bool, path string, err error) {
+	if !strings.Contains(raw, "://") {
+		return raw, nil, "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", nil, "", fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	var plain bool
+	switch u.Scheme {
+	case "http":
+		plain = true
+	case "https":
+	default:
+		return "", nil, "", fmt.Errorf("invalid endpoint %q: scheme must be http or https", raw)
+	}
+	if u.Hostname() == "" {
+		return "", nil, "", fmt.Errorf("invalid endpoint %q: missing host", raw)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if plain {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port), &plain, u.Path, nil
+}
+
+// parseOTLPHeaders parses the "key1=value1,key2=value2" format of
+// OTEL_EXPORTER_OTLP_HEADERS, where values are URL-encoded.
+func parseOTLPHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("header %q must be key=value", strings.TrimSpace(pair))
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", key, err)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
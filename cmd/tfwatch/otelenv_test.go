@@ -0,0 +1,237 @@
+package main
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestApplyOTELEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		wantExit int
+		checks   func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "generic endpoint URL",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "https://otlp.example.com/otlp"},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELEndpoint != "otlp.example.com:443" || cfg.OTELInsecure || cfg.OTELPath != "/otlp/v1/metrics" {
+					t.Errorf("unexpected endpoint config: %q insecure=%v path=%q", cfg.OTELEndpoint, cfg.OTELInsecure, cfg.OTELPath)
+				}
+			},
+		},
+		{
+			name: "metrics endpoint wins and is used as is",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":         "https://otlp.example.com",
+				"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT": "http://collector:4318/custom/metrics",
+				"OTEL_EXPORTER_OTLP_PROTOCOL":         "http/protobuf",
+			},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELEndpoint != "collector:4318" || !cfg.OTELInsecure || cfg.OTELPath != "/custom/metrics" {
+					t.Errorf("unexpected endpoint config: %q insecure=%v path=%q", cfg.OTELEndpoint, cfg.OTELInsecure, cfg.OTELPath)
+				}
+				if cfg.OTELProtocol != "http/protobuf" {
+					t.Errorf("expected protocol from env, got %q", cfg.OTELProtocol)
+				}
+			},
+		},
+		{
+			name: "flags win over env",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":    "http://env:4317",
+				"OTEL_EXPORTER_OTLP_TIMEOUT":     "2500",
+				"OTEL_EXPORTER_OTLP_COMPRESSION": "gzip",
+			},
+			args: []string{"--otel-endpoint", "flag:4317", "--otel-insecure=false", "--otel-timeout", "3s", "--otel-compression", "none"},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELEndpoint != "flag:4317" || cfg.OTELInsecure {
+					t.Errorf("expected flag endpoint with TLS, got %q insecure=%v", cfg.OTELEndpoint, cfg.OTELInsecure)
+				}
+				if cfg.OTELTimeout != 3*time.Second || cfg.OTELCompression != "none" {
+					t.Errorf("expected flag timeout and compression, got %s %q", cfg.OTELTimeout, cfg.OTELCompression)
+				}
+			},
+		},
+		{
+			name: "timeout and compression from env",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_TIMEOUT":     "2500",
+				"OTEL_EXPORTER_OTLP_COMPRESSION": "gzip",
+			},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELTimeout != 2500*time.Millisecond || cfg.OTELCompression != "gzip" {
+					t.Errorf("unexpected timeout/compression: %s %q", cfg.OTELTimeout, cfg.OTELCompression)
+				}
+			},
+		},
+		{
+			name: "headers merged with flags winning",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "api-key=from%20env, x-team=platform"},
+			args: []string{"--otel-header", "api-key=from-flag", "--otel-header", "x-extra=1"},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				want := map[string]string{"api-key": "from-flag", "x-team": "platform", "x-extra": "1"}
+				if !maps.Equal(cfg.OTELHeaders, want) {
+					t.Errorf("expected headers %v, got %v", want, cfg.OTELHeaders)
+				}
+			},
+		},
+		{
+			name: "URL-encoded header value",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_METRICS_HEADERS": "Authorization=Basic%20dXNlcjpwYXNz"},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELHeaders["Authorization"] != "Basic dXNlcjpwYXNz" {
+					t.Errorf("unexpected headers: %v", cfg.OTELHeaders)
+				}
+			},
+		},
+		{
+			name: "http protocol from env defaults to port 4318",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_PROTOCOL": "http/json"},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELEndpoint != "localhost:4318" {
+					t.Errorf("expected localhost:4318, got %q", cfg.OTELEndpoint)
+				}
+			},
+		},
+		{
+			name: "TLS flags switch off insecure",
+			args: []string{"--otel-ca-file", "ca.pem", "--otel-client-cert", "client.pem", "--otel-client-key", "client-key.pem", "--otel-server-name", "collector.internal"},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELInsecure {
+					t.Error("expected a TLS connection")
+				}
+				if cfg.OTELCAFile != "ca.pem" || cfg.OTELClientCert != "client.pem" || cfg.OTELClientKey != "client-key.pem" || cfg.OTELServerName != "collector.internal" {
+					t.Errorf("unexpected TLS config: %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "certificates from env",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_CERTIFICATE":                "env-ca.pem",
+				"OTEL_EXPORTER_OTLP_METRICS_CLIENT_CERTIFICATE": "env-client.pem",
+				"OTEL_EXPORTER_OTLP_CLIENT_KEY":                 "env-client-key.pem",
+			},
+			args: []string{"--otel-ca-file", "flag-ca.pem"},
+			checks: func(t *testing.T, cfg Config) {
+				t.Helper()
+				if cfg.OTELInsecure {
+					t.Error("expected a TLS connection")
+				}
+				if cfg.OTELCAFile != "flag-ca.pem" || cfg.OTELClientCert != "env-client.pem" || cfg.OTELClientKey != "env-client-key.pem" {
+					t.Errorf("unexpected TLS files: %q %q %q", cfg.OTELCAFile, cfg.OTELClientCert, cfg.OTELClientKey)
+				}
+			},
+		},
+		{
+			name:     "TLS flags with explicit insecure",
+			args:     []string{"--otel-insecure=true", "--otel-ca-file", "ca.pem"},
+			wantExit: 1,
+		},
+		{
+			name:     "client certificate without key",
+			args:     []string{"--otel-client-cert", "client.pem"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid header flag",
+			args:     []string{"--otel-header", "novalue"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid header env",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "novalue"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid timeout env",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_TIMEOUT": "10s"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid endpoint scheme",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "grpc://collector:4317"},
+			wantExit: 1,
+		},
+		{
+			name:     "invalid compression env",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_COMPRESSION": "zstd"},
+			wantExit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			wantExit := tt.wantExit
+			if wantExit == 0 {
+				wantExit = -1
+			}
+
+			var cfg Config
+			var exit int
+			captureStdout(func() {
+				cfg, exit = parseFlagsFrom(tt.args)
+			})
+			if exit != wantExit {
+				t.Fatalf("expected exit %d, got %d", wantExit, exit)
+			}
+			if tt.checks != nil {
+				tt.checks(t, cfg)
+			}
+		})
+	}
+}
+
+func TestParseOTLPEndpoint(t *testing.T) {
+	tests := []struct {
+		raw          string
+		wantEndpoint string
+		wantInsecure string // "", "true" or "false"
+		wantPath     string
+		wantErr      bool
+	}{
+		{raw: "collector:4317", wantEndpoint: "collector:4317"},
+		{raw: "https://otlp.example.com", wantEndpoint: "otlp.example.com:443", wantInsecure: "false"},
+		{raw: "http://localhost:4318/v1/metrics", wantEndpoint: "localhost:4318", wantInsecure: "true", wantPath: "/v1/metrics"},
+		{raw: "http://[::1]", wantEndpoint: "[::1]:80", wantInsecure: "true"},
+		{raw: "ftp://example.com", wantErr: true},
+		{raw: "https://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			endpoint, insecure, path, err := parseOTLPEndpoint(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gotInsecure := ""
+			if insecure != nil {
+				gotInsecure = map[bool]string{true: "true", false: "false"}[*insecure]
+			}
+			if endpoint != tt.wantEndpoint || gotInsecure != tt.wantInsecure || path != tt.wantPath {
+				t.Errorf("got (%q, %s, %q), want (%q, %s, %q)", endpoint, gotInsecure, path, tt.wantEndpoint, tt.wantInsecure, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/tfwatch"
+)
+
+// runPublish pushes the payloads in --from, a file written with --otel-file
+// on a host without a collector. It returns exitUndelivered if any are left.
+func runPublish(cfg Config) int {
+	ctx := context.Background()
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		log.Printf("Failed to initialize OTEL: %v", err)
+		return 1
+	}
+	defer func() { _ = exporter.Shutdown(ctx) }()
+
+	delivered, err := tfwatch.PublishFile(ctx, cfg.PublishFrom, exporter)
+	log.Printf("Delivered %d payload(s) from %s to %s", delivered, cfg.PublishFrom, otelTarget(cfg))
+	if err != nil {
+		log.Printf("Failed to publish %s: %v", cfg.PublishFrom, err)
+		return exitUndelivered
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/tfwatch"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// runServe rescans cfg.Directory every cfg.Interval and serves the current
+// state on cfg.Listen until interrupted. It returns the process exit code.
+func runServe(cfg Config) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	res, err := newResource(ctx)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	var exporter sdkmetric.Exporter
+	if cfg.OTELPush {
+		if exporter, err = newExporter(ctx, cfg); err != nil {
+			log.Printf("Failed to initialize OTEL: %v", err)
+			return 1
+		}
+		defer func() { _ = exporter.Shutdown(context.Background()) }()
+	}
+
+	server := tfwatch.NewServer(tfwatch.CollectorConfig{
+		Directory: cfg.Directory,
+		Phase:     cfg.Phase,
+		Versions:  cfg.versions,
+	}, cfg.Directory, cfg.Recursive, res)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", server)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !server.Ready() {
+			http.Error(w, "first scan has not completed yet", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	httpServer := &http.Server{Addr: cfg.Listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- httpServer.ListenAndServe() }()
+	printBanner()
+	fmt.Printf("Serving metrics on %s/metrics, rescanning %s every %s\n", cfg.Listen, cfg.Directory, cfg.Interval)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	code := 0
+loop:
+	for {
+		scanAndServe(ctx, cfg, server, exporter)
+
+		select {
+		case <-ticker.C:
+		case err := <-serveErr:
+			log.Printf("Metrics server failed: %v", err)
+			code = 1
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Failed to stop metrics server: %v", err)
+	}
+	return code
+}
+
+// scanAndServe runs one scan, swaps it into server and optionally pushes the
+// same snapshot via OTLP. Errors are logged; the daemon keeps running.
+func scanAndServe(ctx context.Context, cfg Config, server *tfwatch.Server, exporter sdkmetric.Exporter) {
+	start := time.Now()
+	results, err := scanAll(ctx, cfg)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	rm, err := server.Update(ctx, results)
+	if err != nil {
+		log.Printf("Failed to update metrics: %v", err)
+		return
+	}
+	log.Printf("Scanned %d root(s) in %s", len(results), time.Since(start).Round(time.Millisecond))
+
+	if exporter != nil {
+		if err := exporter.Export(ctx, rm); err != nil {
+			log.Printf("Failed to push metrics to %s: %v", otelTarget(cfg), err)
+		}
+	}
+}
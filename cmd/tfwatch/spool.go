@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/tfwatch"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// runFlushSpool delivers the payloads in --spool-dir. It returns
+// exitUndelivered if any are left.
+func runFlushSpool(cfg Config) int {
+	ctx := context.Background()
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		log.Printf("Failed to initialize OTEL: %v", err)
+		return 1
+	}
+	defer func() { _ = exporter.Shutdown(ctx) }()
+
+	if !flushSpool(ctx, cfg, exporter) {
+		return exitUndelivered
+	}
+	return 0
+}
+
+// flushSpool replays --spool-dir with exporter and reports whether it is
+// empty now. Failures are logged; the payloads stay for the next run.
+func flushSpool(ctx context.Context, cfg Config, exporter sdkmetric.Exporter) bool {
+	delivered, err := tfwatch.FlushSpool(ctx, cfg.SpoolDir, exporter)
+	if delivered > 0 {
+		// Logged rather than printed: stdout may carry --output.
+		log.Printf("Delivered %d spooled payload(s) from %s to %s", delivered, cfg.SpoolDir, otelTarget(cfg))
+	}
+	if err != nil {
+		log.Printf("Failed to flush %s: %v", cfg.SpoolDir, err)
+		return false
+	}
+	return true
+}
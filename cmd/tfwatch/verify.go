@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/tfwatch"
+)
+
+// runVerify checks the provider packages installed in --dir, or in every
+// root module under it with --recursive, against the lock file hashes. It
+// never runs terraform init or goes to the network. It returns
+// exitCheckFailed if a root could not be verified, exitViolations if a
+// package is missing or does not match, and 0 otherwise.
+func runVerify(cfg Config) int {
+	dirs := []string{cfg.Directory}
+	if cfg.Recursive {
+		var err error
+		if dirs, err = discoverRoots(cfg); err != nil {
+			log.Print(err)
+			return exitCheckFailed
+		}
+	}
+
+	printBanner()
+	failed, problems := tfwatch.VerifyRoots(cfg.Directory, dirs)
+	switch {
+	case failed > 0:
+		return exitCheckFailed
+	case problems > 0:
+		return exitViolations
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/tfwatch"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// runWatch scans and publishes every root once, then rescans and re-publishes
+// a single root whenever its lock file, modules.json or *.tf files change,
+// until interrupted. With --list the dependencies are printed instead of
+// published. It returns the process exit code.
+func runWatch(cfg Config) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	roots := []string{cfg.Directory}
+	if cfg.Recursive {
+		var err error
+		if roots, err = discoverRoots(cfg); err != nil {
+			log.Print(err)
+			return 1
+		}
+	}
+
+	watcher, err := tfwatch.NewWatcher(roots, cfg.Debounce)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer watcher.Close()
+
+	printBanner()
+	var publish func(dirs []string)
+	if cfg.ListOnly {
+		publish = func(dirs []string) {
+			tfwatch.ListRoots(ctx, cfg.Directory, dirs, scanOptions(cfg)).Print()
+		}
+	} else {
+		provider, err := newWatchProvider(ctx, cfg)
+		if err != nil {
+			log.Printf("Failed to initialize OTEL: %v", err)
+			return 1
+		}
+		defer func() { _ = provider.Shutdown(context.Background()) }()
+
+		// The provider keeps exporting every series it has seen, so series
+		// replaced by a rescan must be retired even without --state-file.
+		state, err := loadState(cfg)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		if state == nil {
+			state = tfwatch.NewSeriesState()
+		}
+
+		collector := tfwatch.NewCollector(tfwatch.CollectorConfig{
+			Directory:     cfg.Directory,
+			Phase:         cfg.Phase,
+			OTELEndpoint:  otelTarget(cfg),
+			Versions:      cfg.versions,
+			Concurrency:   cfg.Concurrency,
+			NoInit:        cfg.NoInit,
+			MeterProvider: provider,
+			State:         state,
+		})
+		publish = func(dirs []string) {
+			if cfg.Recursive {
+				collector.CollectRoots(ctx, cfg.Directory, dirs, scanOptions(cfg)).Print()
+			} else if err := collector.Collect(ctx); err != nil {
+				log.Printf("Failed to collect dependencies: %v", err)
+			}
+			if cfg.StateFile != "" {
+				if err := saveState(cfg, state); err != nil {
+					log.Print(err)
+				}
+			}
+			// Push now rather than at the next export interval so that
+			// the change shows up while the developer is looking.
+			if err := provider.ForceFlush(ctx); err != nil {
+				log.Printf("Failed to publish metrics to %s: %v", otelTarget(cfg), err)
+			}
+		}
+	}
+
+	publish(roots)
+	fmt.Printf("\nWatching %d root module(s) in %s for changes\n", len(roots), cfg.Directory)
+
+	watcher.Run(ctx, func(root string) {
+		log.Printf("Change detected in %s", root)
+		publish([]string{root})
+	})
+	return 0
+}
+
+// newWatchProvider creates a MeterProvider that exports via OTLP. Unlike
+// initOTEL it is not installed globally, so that it can be flushed after every
+// rescan.
+func newWatchProvider(ctx context.Context, cfg Config) (*sdkmetric.MeterProvider, error) {
+	res, err := newResource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		// The reader's own timeout (30s) must not cut the exporter's
+		// retries short.
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithTimeout(cfg.OTELDeadline+cfg.OTELTimeout))),
+	), nil
+}
@@ -0,0 +1,145 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraints is a list of version conditions that must all hold, parsed
+// from a comma-separated string such as ">= 1.2.0, < 2.0.0, != 1.5.0".
+type Constraints []Constraint
+
+// Constraint is a single "<operator> <version>" condition.
+type Constraint struct {
+	Op      string // "=", "!=", ">", ">=", "<", "<=" or "~>"
+	Version Version
+}
+
+// operators is ordered so that two-character operators are matched first.
+var operators = []string{"~>", ">=", "<=", "!=", "=", ">", "<"}
+
+// ParseConstraints parses a Terraform version constraint string. A term
+// without an operator is an exact match, as with "=".
+func ParseConstraints(s string) (Constraints, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("invalid version constraint %q: empty", s)
+	}
+
+	var cs Constraints
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		op := "="
+		for _, o := range operators {
+			if strings.HasPrefix(term, o) {
+				op, term = o, strings.TrimSpace(term[len(o):])
+				break
+			}
+		}
+		v, err := Parse(term)
+		if err != nil || term == "" {
+			return nil, fmt.Errorf("invalid version constraint %q", s)
+		}
+		cs = append(cs, Constraint{Op: op, Version: v})
+	}
+	return cs, nil
+}
+
+// MustParseConstraints is like ParseConstraints but panics on error.
+func MustParseConstraints(s string) Constraints {
+	cs, err := ParseConstraints(s)
+	if err != nil {
+		panic(err)
+	}
+	return cs
+}
+
+// Check reports whether v satisfies every constraint.
+//
+// As in Terraform, a prerelease version is only selected by an exact
+// constraint naming it: "1.2.0-beta" satisfies "= 1.2.0-beta" (or
+// "1.2.0-beta, < 2.0.0") but not ">= 1.0.0" or "~> 1.2.0-alpha".
+func (cs Constraints) Check(v Version) bool {
+	if len(cs) == 0 {
+		return false
+	}
+	if v.IsPrerelease() {
+		exact := false
+		for _, c := range cs {
+			if c.Op == "=" && c.Version.Equal(v) {
+				exact = true
+			}
+		}
+		if !exact {
+			return false
+		}
+	}
+
+	for _, c := range cs {
+		if !c.Check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports whether v satisfies the single condition c, without the
+// prerelease rule applied by Constraints.Check.
+func (c Constraint) Check(v Version) bool {
+	d := v.Compare(c.Version)
+	switch c.Op {
+	case "=":
+		return d == 0
+	case "!=":
+		return d != 0
+	case ">":
+		return d > 0
+	case ">=":
+		return d >= 0
+	case "<":
+		return d < 0
+	case "<=":
+		return d <= 0
+	case "~>":
+		// Only the rightmost written component may increase: "~> 1.2"
+		// allows 1.x from 1.2 and "~> 1.2.0" allows 1.2.x. A single
+		// component behaves like two, so "~> 1" allows 1.x.
+		if d < 0 {
+			return false
+		}
+		if c.Version.segments <= 2 {
+			return v.Major == c.Version.Major
+		}
+		return v.Major == c.Version.Major && v.Minor == c.Version.Minor
+	}
+	return false
+}
+
+// String returns the constraint as written, with versions normalized.
+func (c Constraint) String() string {
+	return c.Op + " " + c.Version.written()
+}
+
+// String returns the constraints in Terraform syntax.
+func (cs Constraints) String() string {
+	terms := make([]string, len(cs))
+	for i, c := range cs {
+		terms[i] = c.String()
+	}
+	return strings.Join(terms, ", ")
+}
+
+// written returns the version with as many components as were written, which
+// matters for "~>": "~> 1.2" and "~> 1.2.0" are different constraints.
+func (v Version) written() string {
+	n := v.segments
+	if n == 0 {
+		n = 3
+	}
+	parts := []string{strconv.FormatUint(v.Major, 10), strconv.FormatUint(v.Minor, 10), strconv.FormatUint(v.Patch, 10)}
+	s := strings.Join(parts[:n], ".")
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+// Package semver parses and compares the version numbers and version
+// constraints used by Terraform for providers, modules and the CLI itself.
+//
+// Versions follow Semantic Versioning 2.0.0 with Terraform's leniency:
+// a leading "v" is accepted and missing minor or patch components default to
+// zero, so "1.2" and "v1.2.0" are the same version. Constraints are the
+// comma-separated lists accepted by version and required_version arguments,
+// e.g. ">= 1.2.0, < 2.0.0" or "~> 1.9".
+package semver
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed version number.
+type Version struct {
+	Major, Minor, Patch uint64
+	Prerelease          string // dot-separated identifiers after "-", e.g. "beta.1"
+	Metadata            string // build metadata after "+"; ignored for ordering
+
+	segments int // components written in the original string (1-3)
+}
+
+// Parse parses a version string such as "1.2.3", "v1.2", "1.0.0-beta.1" or
+// "1.0.0+build.5".
+func Parse(s string) (Version, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+
+	var v Version
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s, v.Metadata = s[:i], s[i+1:]
+		if !validIdentifiers(v.Metadata) {
+			return Version{}, fmt.Errorf("invalid version %q: malformed build metadata", raw)
+		}
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, v.Prerelease = s[:i], s[i+1:]
+		if !validIdentifiers(v.Prerelease) {
+			return Version{}, fmt.Errorf("invalid version %q: malformed prerelease", raw)
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q: more than three components", raw)
+	}
+	nums := [3]uint64{}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q", raw)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	v.segments = len(parts)
+	return v, nil
+}
+
+// MustParse is like Parse but panics on error. It is intended for tests and
+// constant versions.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func validIdentifiers(s string) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsPrerelease reports whether v has a prerelease suffix.
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Compare returns -1, 0 or +1 depending on whether v sorts before, equal to
+// or after o, using semver precedence: a prerelease sorts before its release,
+// prerelease identifiers are compared numerically when both are numbers and
+// lexically otherwise, and build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	if c := cmp.Compare(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1 // numeric identifiers have lower precedence
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// Equal reports whether v and o have the same precedence.
+func (v Version) Equal(o Version) bool {
+	return v.Compare(o) == 0
+}
+
+// LessThan reports whether v sorts before o.
+func (v Version) LessThan(o Version) bool {
+	return v.Compare(o) < 0
+}
+
+// String returns the normalized "major.minor.patch[-prerelease][+metadata]" form.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Metadata != "" {
+		s += "+" + v.Metadata
+	}
+	return s
+}
@@ -0,0 +1,194 @@
+package semver
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "1.2.3", want: "1.2.3"},
+		{input: "v1.2.3", want: "1.2.3"},
+		{input: "1.2", want: "1.2.0"},
+		{input: "1", want: "1.0.0"},
+		{input: " 5.75.1 ", want: "5.75.1"},
+		{input: "1.0.0-beta.1", want: "1.0.0-beta.1"},
+		{input: "1.0.0+build.5", want: "1.0.0+build.5"},
+		{input: "1.0.0-rc1+20240101", want: "1.0.0-rc1+20240101"},
+		{input: "1.0.0-x-y-z", want: "1.0.0-x-y-z"},
+		{input: "", wantErr: true},
+		{input: "1.2.3.4", wantErr: true},
+		{input: "1.a.3", wantErr: true},
+		{input: "-1.0.0", wantErr: true},
+		{input: "1.0.0-", wantErr: true},
+		{input: "1.0.0-beta..1", wantErr: true},
+		{input: "1.0.0+", wantErr: true},
+		{input: "1.0.0-beta_1", wantErr: true},
+		{input: "main", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, err := Parse(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := v.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.2.4", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.0.0+a", "1.0.0+b", 0},
+		// Semver 2.0.0 §11 precedence examples.
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta", "1.0.0-beta.2", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, b := MustParse(tt.a), MustParse(tt.b)
+			if got := a.Compare(b); got != tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.Compare(a); got != -tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestConstraints_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		// Exact and negated matches.
+		{"1.2.0", "1.2.0", true},
+		{"= 1.2.0", "1.2.0", true},
+		{"=1.2", "1.2.0", true},
+		{"= 1.2.0", "1.2.1", false},
+		{"!= 1.2.0", "1.2.1", true},
+		{"!= 1.2.0", "1.2.0", false},
+
+		// Comparisons.
+		{"> 1.2.0", "1.2.1", true},
+		{"> 1.2.0", "1.2.0", false},
+		{">= 5.60", "5.60.0", true},
+		{">= 5.60", "5.59.9", false},
+		{"< 2.0.0", "1.99.0", true},
+		{"<= 2.0.0", "2.0.0", true},
+		{"<= 2.0.0", "2.0.1", false},
+
+		// Comma lists must all hold.
+		{">= 1.2.0, < 2.0.0", "1.5.0", true},
+		{">= 1.2.0, < 2.0.0", "2.0.0", false},
+		{">= 1.2.0, < 2.0.0, != 1.5.0", "1.5.0", false},
+		{">=1.0,<1.1", "1.0.9", true},
+
+		// Pessimistic constraint: only the rightmost written component may increase.
+		{"~> 1.2.0", "1.2.0", true},
+		{"~> 1.2.0", "1.2.10", true},
+		{"~> 1.2.0", "1.3.0", false},
+		{"~> 1.2.3", "1.2.2", false},
+		{"~> 1.2", "1.2.0", true},
+		{"~> 1.2", "1.9.0", true},
+		{"~> 1.2", "2.0.0", false},
+		{"~> 1.2", "1.1.9", false},
+		{"~> 1", "1.9.0", true},
+		{"~> 1", "2.0.0", false},
+		{"~> 0.12.0", "0.12.31", true},
+		{"~> 0.12.0", "0.13.0", false},
+
+		// Prereleases are only selected by an exact constraint naming them.
+		{"1.2.0-beta", "1.2.0-beta", true},
+		{"= 1.2.0-beta", "1.2.0-beta", true},
+		{"1.2.0-beta, < 2.0.0", "1.2.0-beta", true},
+		{">= 1.0.0", "1.2.0-beta", false},
+		{">= 1.2.0-alpha", "1.2.0-beta", false},
+		{"~> 1.2.0-alpha", "1.2.0-beta", false},
+		{"!= 1.2.0", "1.2.0-beta", false},
+		{"= 1.2.0-beta", "1.2.0-rc1", false},
+		// A prerelease bound still orders releases normally.
+		{">= 1.2.0-beta", "1.2.0", true},
+		{"< 1.2.0-beta", "1.1.9", true},
+
+		// Build metadata is ignored.
+		{"= 1.2.0", "1.2.0+linux", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint+" / "+tt.version, func(t *testing.T) {
+			cs := MustParseConstraints(tt.constraint)
+			if got := cs.Check(MustParse(tt.version)); got != tt.want {
+				t.Errorf("%q.Check(%s) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConstraints(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: ">= 1.2", want: ">= 1.2"},
+		{input: "~>1.2.0", want: "~> 1.2.0"},
+		{input: "1.0", want: "= 1.0"},
+		{input: " >= 1.0 ,< 2 ", want: ">= 1.0, < 2"},
+		{input: "!= 1.0.0-beta", want: "!= 1.0.0-beta"},
+		{input: "", wantErr: true},
+		{input: ">=", wantErr: true},
+		{input: ">= 1.0,", wantErr: true},
+		{input: "=> 1.0", wantErr: true},
+		{input: "~> latest", wantErr: true},
+		{input: ">= 1.0 < 2.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			cs, err := ParseConstraints(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", cs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cs.String(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
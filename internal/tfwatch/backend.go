@@ -0,0 +1,381 @@
+package tfwatch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// BackendConfig describes the detected Terraform backend. Type is "workspace"
+// for a cloud {} block and the backend label (e.g. "s3", "gcs") otherwise;
+// only the fields relevant to that type are populated.
+type BackendConfig struct {
+	Type           string `json:"type" yaml:"type"`                                           // "workspace", "s3", "gcs", "azurerm", "remote", ...
+	Hostname       string `json:"hostname,omitempty" yaml:"hostname,omitempty"`               // cloud/remote backend: TFC/TFE hostname
+	Organization   string `json:"organization,omitempty" yaml:"organization,omitempty"`       // cloud/remote backend: tf_org
+	Workspace      string `json:"workspace,omitempty" yaml:"workspace,omitempty"`             // cloud/remote backend: workspace name (or prefix)
+	Bucket         string `json:"bucket,omitempty" yaml:"bucket,omitempty"`                   // s3/gcs/oss/cos backend: bucket name
+	Key            string `json:"key,omitempty" yaml:"key,omitempty"`                         // s3/gcs/oss/cos/azurerm backend: normalized key or prefix (slashes → underscores)
+	StorageAccount string `json:"storage_account,omitempty" yaml:"storage_account,omitempty"` // azurerm backend: storage_account_name
+	Container      string `json:"container,omitempty" yaml:"container,omitempty"`             // azurerm backend: container_name
+	Address        string `json:"address,omitempty" yaml:"address,omitempty"`                 // consul/http/pg backend: server host (credentials stripped)
+	Path           string `json:"path,omitempty" yaml:"path,omitempty"`                       // consul/http/local backend: normalized state path
+	Namespace      string `json:"namespace,omitempty" yaml:"namespace,omitempty"`             // kubernetes backend: namespace
+	SecretSuffix   string `json:"secret_suffix,omitempty" yaml:"secret_suffix,omitempty"`     // kubernetes backend: secret_suffix
+	Schema         string `json:"schema,omitempty" yaml:"schema,omitempty"`                   // pg backend: schema_name
+}
+
+// Identity returns the values published as backend_org and backend_workspace.
+// Every backend type maps its identifying attributes onto this pair so that
+// queries can group deployments without knowing which backend a repo uses.
+func (b *BackendConfig) Identity() (org, workspace string) {
+	switch b.Type {
+	case "workspace", "remote":
+		return b.Organization, b.Workspace
+	case "s3", "gcs", "oss", "cos":
+		return b.Bucket, b.Key
+	case "azurerm":
+		return b.StorageAccount, joinNonEmpty("_", b.Container, b.Key)
+	case "consul", "http":
+		return b.Address, b.Path
+	case "pg":
+		return b.Address, b.Schema
+	case "kubernetes":
+		return b.Namespace, b.SecretSuffix
+	case "local":
+		return "", b.Path
+	}
+	return "", ""
+}
+
+// backendField is a single human-readable line of backend details.
+type backendField struct {
+	label string
+	value string
+}
+
+// fields returns the type-specific details shown in the CLI banner.
+func (b *BackendConfig) fields() []backendField {
+	switch b.Type {
+	case "workspace":
+		return []backendField{
+			{"Organization", b.Organization},
+			{"Workspace", b.Workspace},
+		}
+	case "remote":
+		return []backendField{
+			{"Hostname", b.Hostname},
+			{"Organization", b.Organization},
+			{"Workspace", b.Workspace},
+		}
+	case "s3":
+		return []backendField{
+			{"S3 Bucket", b.Bucket},
+			{"S3 Key", b.Key},
+		}
+	case "gcs":
+		return []backendField{
+			{"GCS Bucket", b.Bucket},
+			{"GCS Prefix", b.Key},
+		}
+	case "oss":
+		return []backendField{
+			{"OSS Bucket", b.Bucket},
+			{"OSS Key", b.Key},
+		}
+	case "cos":
+		return []backendField{
+			{"COS Bucket", b.Bucket},
+			{"COS Key", b.Key},
+		}
+	case "azurerm":
+		return []backendField{
+			{"Storage Account", b.StorageAccount},
+			{"Container", b.Container},
+			{"Key", b.Key},
+		}
+	case "consul", "http":
+		return []backendField{
+			{"Address", b.Address},
+			{"Path", b.Path},
+		}
+	case "pg":
+		return []backendField{
+			{"Host", b.Address},
+			{"Schema", b.Schema},
+		}
+	case "kubernetes":
+		return []backendField{
+			{"Namespace", b.Namespace},
+			{"Secret Suffix", b.SecretSuffix},
+		}
+	case "local":
+		return []backendField{
+			{"Path", b.Path},
+		}
+	}
+	return nil
+}
+
+// printBackend writes the backend type and its details in banner format.
+func printBackend(backend *BackendConfig) {
+	fmt.Printf("Backend Type:      %s\n", backend.Type)
+	for _, f := range backend.fields() {
+		fmt.Printf("%-19s%s\n", f.label+":", f.value)
+	}
+}
+
+// backendParsers maps a backend "<type>" label to the function that extracts
+// its identifying attributes. Types not listed here are not detected.
+var backendParsers = map[string]func(body hcl.Body, attrs hcl.Attributes) *BackendConfig{
+	"s3":         parseS3Backend,
+	"gcs":        parseGCSBackend,
+	"azurerm":    parseAzureRMBackend,
+	"remote":     parseRemoteBackend,
+	"http":       parseHTTPBackend,
+	"consul":     parseConsulBackend,
+	"pg":         parsePGBackend,
+	"kubernetes": parseKubernetesBackend,
+	"local":      parseLocalBackend,
+	"oss":        parseOSSBackend,
+	"cos":        parseCOSBackend,
+}
+
+func (p *Parser) parseCloudBlock(tfBlock *hcl.Block) *BackendConfig {
+	content, _, diag := tfBlock.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "cloud"},
+		},
+	})
+	if diag.HasErrors() || len(content.Blocks) == 0 {
+		return nil
+	}
+
+	cloudBlock := content.Blocks[0]
+	attrs, _ := cloudBlock.Body.JustAttributes()
+
+	return &BackendConfig{
+		Type:         "workspace",
+		Hostname:     stringAttrOr(attrs, "hostname", "app.terraform.io"),
+		Organization: stringAttr(attrs, "organization"),
+		Workspace:    workspacesBlockName(cloudBlock.Body),
+	}
+}
+
+func (p *Parser) parseBackendBlock(tfBlock *hcl.Block) *BackendConfig {
+	content, _, diag := tfBlock.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "backend", LabelNames: []string{"type"}},
+		},
+	})
+	if diag.HasErrors() || len(content.Blocks) == 0 {
+		return nil
+	}
+
+	for _, block := range content.Blocks {
+		if len(block.Labels) == 0 {
+			continue
+		}
+		parse, ok := backendParsers[block.Labels[0]]
+		if !ok {
+			continue
+		}
+
+		attrs, _ := block.Body.JustAttributes()
+		return parse(block.Body, attrs)
+	}
+
+	return nil
+}
+
+func parseS3Backend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:   "s3",
+		Bucket: stringAttr(attrs, "bucket"),
+		Key:    normalizeKey(stringAttr(attrs, "key")),
+	}
+}
+
+func parseGCSBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:   "gcs",
+		Bucket: stringAttr(attrs, "bucket"),
+		Key:    normalizeKey(stringAttr(attrs, "prefix")),
+	}
+}
+
+func parseAzureRMBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:           "azurerm",
+		StorageAccount: stringAttr(attrs, "storage_account_name"),
+		Container:      stringAttr(attrs, "container_name"),
+		Key:            normalizeKey(stringAttr(attrs, "key")),
+	}
+}
+
+func parseRemoteBackend(body hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:         "remote",
+		Hostname:     stringAttrOr(attrs, "hostname", "app.terraform.io"),
+		Organization: stringAttr(attrs, "organization"),
+		Workspace:    workspacesBlockName(body),
+	}
+}
+
+func parseHTTPBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	cfg := &BackendConfig{Type: "http"}
+	// Only the host and path are kept: the address may embed basic-auth
+	// credentials, which must never end up in a metric label.
+	if u, err := url.Parse(stringAttr(attrs, "address")); err == nil {
+		cfg.Address = u.Host
+		cfg.Path = normalizeKey(strings.TrimPrefix(u.Path, "/"))
+	}
+	return cfg
+}
+
+func parseConsulBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:    "consul",
+		Address: stringAttr(attrs, "address"),
+		Path:    normalizeKey(stringAttr(attrs, "path")),
+	}
+}
+
+func parsePGBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:    "pg",
+		Address: pgHost(stringAttr(attrs, "conn_str")),
+		Schema:  stringAttrOr(attrs, "schema_name", "terraform_remote_state"),
+	}
+}
+
+func parseKubernetesBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:         "kubernetes",
+		Namespace:    stringAttrOr(attrs, "namespace", "default"),
+		SecretSuffix: stringAttr(attrs, "secret_suffix"),
+	}
+}
+
+func parseLocalBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type: "local",
+		Path: normalizeKey(stringAttrOr(attrs, "path", "terraform.tfstate")),
+	}
+}
+
+func parseOSSBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:   "oss",
+		Bucket: stringAttr(attrs, "bucket"),
+		Key:    normalizeKey(joinNonEmpty("/", stringAttr(attrs, "prefix"), stringAttrOr(attrs, "key", "terraform.tfstate"))),
+	}
+}
+
+func parseCOSBackend(_ hcl.Body, attrs hcl.Attributes) *BackendConfig {
+	return &BackendConfig{
+		Type:   "cos",
+		Bucket: stringAttr(attrs, "bucket"),
+		Key:    normalizeKey(joinNonEmpty("/", stringAttr(attrs, "prefix"), stringAttrOr(attrs, "key", "terraform.tfstate"))),
+	}
+}
+
+// workspacesBlockName reads the nested workspaces {} block shared by the cloud
+// block and the remote backend, preferring name over prefix.
+func workspacesBlockName(body hcl.Body) string {
+	content, _, _ := body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "workspaces"},
+		},
+	})
+	if content == nil || len(content.Blocks) == 0 {
+		return ""
+	}
+
+	attrs, _ := content.Blocks[0].Body.JustAttributes()
+	if name := stringAttr(attrs, "name"); name != "" {
+		return name
+	}
+	return stringAttr(attrs, "prefix")
+}
+
+// pgHost extracts the host from a pg conn_str in either URL or keyword/value
+// form. The rest of the connection string is dropped since it may hold a password.
+func pgHost(connStr string) string {
+	if u, err := url.Parse(connStr); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	for _, field := range strings.Fields(connStr) {
+		if v, ok := strings.CutPrefix(field, "host="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// stringAttr returns the string value of a literal attribute, or "" if it is
+// missing or cannot be evaluated without context (e.g. a variable reference).
+func stringAttr(attrs hcl.Attributes, name string) string {
+	return stringAttrOr(attrs, name, "")
+}
+
+// stringAttrOr is like stringAttr but returns def when the attribute is unset.
+func stringAttrOr(attrs hcl.Attributes, name, def string) string {
+	v, ok := attrs[name]
+	if !ok {
+		return def
+	}
+
+	val, diag := v.Expr.Value(nil)
+	if diag.HasErrors() || val.IsNull() || !val.IsWhollyKnown() {
+		return def
+	}
+	val, err := convert.Convert(val, cty.String)
+	if err != nil {
+		return def
+	}
+	return val.AsString()
+}
+
+// stringListAttr returns the string elements of a literal list attribute,
+// skipping anything that is not a known string.
+func stringListAttr(attrs hcl.Attributes, name string) []string {
+	v, ok := attrs[name]
+	if !ok {
+		return nil
+	}
+
+	val, diag := v.Expr.Value(nil)
+	if diag.HasErrors() || val.IsNull() || !val.IsWhollyKnown() || !val.CanIterateElements() {
+		return nil
+	}
+
+	var out []string
+	for it := val.ElementIterator(); it.Next(); {
+		_, elem := it.Element()
+		if elem.IsNull() || elem.Type() != cty.String {
+			continue
+		}
+		out = append(out, elem.AsString())
+	}
+	return out
+}
+
+// normalizeKey replaces slashes with underscores to produce cleaner label values.
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(key, "/", "_")
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log"
 	"os/exec"
+	"slices"
+	"strings"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -17,32 +19,71 @@ type CollectorConfig struct {
 	Directory    string
 	Phase        string
 	OTELEndpoint string
+	Quiet        bool // suppress the human-readable banner and per-dependency lines
+
+	// Versions, if set, is used to look up the latest version of each
+	// dependency for roots that were not already checked with CheckOutdated.
+	Versions VersionSource
+	// Concurrency limits the Versions lookups in flight for one root.
+	Concurrency int
+
+	// MeterProvider records the metrics; the global provider is used if nil.
+	MeterProvider metric.MeterProvider
+
+	// NoInit makes Collect parse module blocks instead of running
+	// terraform init; see ScanOptions.
+	NoInit bool
+
+	// State, if set, holds the series published by previous runs. Series of
+	// a root that are not published again are recorded as 0, and State is
+	// updated with the new series; the caller saves it.
+	State *SeriesState
 }
 
 // Collector publishes Terraform dependency metrics via OpenTelemetry.
 type Collector struct {
 	config    CollectorConfig
-	gauge     metric.Int64Gauge
+	gauges    map[string]metric.Int64Gauge // by metric name
 	tfVersion string
+	recorded  []PublishedSeries // series recorded for the current root when State is set
+
+	constraints map[dependencyKey]string // version constraints of the current root's dependencies
 }
 
 // Module represents a Terraform module dependency.
 type Module struct {
-	Name    string
-	Source  string
-	Version string
+	Name       string `json:"name" yaml:"name"`
+	Source     string `json:"source" yaml:"source"`
+	Version    string `json:"version" yaml:"version"`                           // resolved by terraform init; "" without
+	Constraint string `json:"constraint,omitempty" yaml:"constraint,omitempty"` // version argument of the module block
+	Location   string `json:"location,omitempty" yaml:"location,omitempty"`     // module block as "file:line", relative to the root
 }
 
 // Provider represents a Terraform provider dependency.
 type Provider struct {
-	Name    string
-	Source  string
-	Version string
+	Name            string               `json:"name" yaml:"name"`
+	Source          string               `json:"source" yaml:"source"`
+	Version         string               `json:"version" yaml:"version"`
+	Hashes          []string             `json:"hashes,omitempty" yaml:"hashes,omitempty"`                     // lock file hashes ("h1:..." / "zh:...")
+	LockConstraints string               `json:"lock_constraints,omitempty" yaml:"lock_constraints,omitempty"` // constraints recorded in the lock file
+	Constraints     []ProviderConstraint `json:"constraints,omitempty" yaml:"constraints,omitempty"`           // required_providers entries for the source
+}
+
+// ProviderConstraint is the version constraint one module declares for a
+// provider in required_providers.
+type ProviderConstraint struct {
+	Module     string `json:"module" yaml:"module"`         // module key; "" for the root module
+	Constraint string `json:"constraint" yaml:"constraint"` // "" if the entry has no version
+	Location   string `json:"location" yaml:"location"`     // entry as "file:line", relative to the root
 }
 
-// NewCollector creates a Collector with an OTEL gauge metric.
+// NewCollector creates a Collector with its OTEL gauge metrics.
 func NewCollector(cfg CollectorConfig) *Collector {
-	meter := otel.Meter("tfwatch")
+	provider := cfg.MeterProvider
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	meter := provider.Meter("tfwatch")
 
 	gauge, err := meter.Int64Gauge(
 		"terraform_dependency_version",
@@ -52,76 +93,205 @@ func NewCollector(cfg CollectorConfig) *Collector {
 		log.Fatalf("Failed to create gauge: %v", err)
 	}
 
+	behind, err := meter.Int64Gauge(
+		"terraform_dependency_versions_behind",
+		metric.WithDescription("Newer releases available for a dependency, by update_type (major, minor or patch)"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create gauge: %v", err)
+	}
+
+	latest, err := meter.Int64Gauge(
+		"terraform_dependency_latest_version_info",
+		metric.WithDescription("Latest available version of a dependency (in the latest_version label)"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create gauge: %v", err)
+	}
+
+	violation, err := meter.Int64Gauge(
+		"terraform_policy_violation",
+		metric.WithDescription("Policy rules violated by a root module (rule and severity in labels)"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create gauge: %v", err)
+	}
+
+	lockIssue, err := meter.Int64Gauge(
+		"terraform_lock_issue",
+		metric.WithDescription("Providers whose lock file entry is unused, missing or outside a declared constraint (in the issue label)"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create gauge: %v", err)
+	}
+
 	tfVer := getTerraformVersion()
 
 	return &Collector{
 		config:    cfg,
-		gauge:     gauge,
 		tfVersion: tfVer,
+		gauges: map[string]metric.Int64Gauge{
+			"terraform_dependency_version":             gauge,
+			"terraform_dependency_versions_behind":     behind,
+			"terraform_dependency_latest_version_info": latest,
+			"terraform_policy_violation":               violation,
+			"terraform_lock_issue":                     lockIssue,
+		},
 	}
 }
 
 // Collect parses dependencies and publishes them as OTEL metrics.
 func (c *Collector) Collect(ctx context.Context) error {
 	parser := NewParser(c.config.Directory)
+	parser.noInit = c.config.NoInit
+	return c.Publish(ctx, scanDir(parser))
+}
 
-	backend, err := parser.ParseBackend()
-	if err != nil {
-		return fmt.Errorf("failed to detect backend: %w", err)
+// CollectRoots scans every directory in dirs in parallel according to opts
+// and publishes the results in the order of dirs. See PublishRoots.
+func (c *Collector) CollectRoots(ctx context.Context, root string, dirs []string, opts ScanOptions) *ScanSummary {
+	return c.PublishRoots(ctx, root, ScanRoots(ctx, dirs, opts))
+}
+
+// PublishRoots publishes already-scanned results, adding a "directory" label
+// with each path relative to root. A failing root is recorded in the summary
+// and does not stop the remaining roots.
+func (c *Collector) PublishRoots(ctx context.Context, root string, results []ScanResult) *ScanSummary {
+	summary := &ScanSummary{}
+	for _, res := range results {
+		rel := relativeDir(root, res.Directory)
+		if res.Err != nil {
+			log.Printf("Error: %s: %v", rel, res.Err)
+			summary.Failed = append(summary.Failed, RootError{Directory: rel, Err: res.Err})
+			continue
+		}
+		c.publishResult(ctx, res, directoryAttrs(root, res))
+		summary.Scanned = append(summary.Scanned, rel)
 	}
+	return summary
+}
 
-	fmt.Printf("\nDirectory:         %s\n", c.config.Directory)
-	fmt.Printf("Phase:             %s\n", c.config.Phase)
-	fmt.Printf("Backend Type:      %s\n", backend.Type)
-	switch backend.Type {
-	case "workspace":
-		fmt.Printf("Organization:      %s\n", backend.Organization)
-		fmt.Printf("Workspace:         %s\n", backend.Workspace)
-	case "s3":
-		fmt.Printf("S3 Bucket:         %s\n", backend.Bucket)
-		fmt.Printf("S3 Key:            %s\n", backend.Key)
+// RetireMissingRoots records 0 for the stored series of every root below root
+// that is not in dirs, and forgets them. Call it after a complete discovery
+// of root, to retire root modules that were deleted; a failed root is still
+// in dirs and keeps its series. It does nothing if State is not set.
+func (c *Collector) RetireMissingRoots(ctx context.Context, root string, dirs []string) {
+	if c.config.State == nil {
+		return
+	}
+	keep := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		keep[stateKey(dir)] = true
 	}
+	c.retire(ctx, c.config.State.removeMissing(root, c.config.Phase, keep))
+}
+
+// directoryAttrs returns the "directory" label of a root in a multi-root scan.
+func directoryAttrs(root string, res ScanResult) []attribute.KeyValue {
+	return []attribute.KeyValue{attribute.String("directory", relativeDir(root, res.Directory))}
+}
+
+// Publish records the metrics for a single scanned root without a directory label.
+func (c *Collector) Publish(ctx context.Context, res ScanResult) error {
+	if res.Err != nil {
+		return res.Err
+	}
+	c.publishResult(ctx, res, nil)
+	return nil
+}
+
+// publishResult prints the run banner for a scanned root and records one
+// data point per dependency.
+func (c *Collector) publishResult(ctx context.Context, res ScanResult, extra []attribute.KeyValue) {
+	if c.config.Versions != nil && res.Outdated == nil {
+		checked := []ScanResult{res}
+		CheckOutdated(ctx, c.config.Versions, checked, c.config.Concurrency)
+		res = checked[0]
+		for _, d := range res.Outdated {
+			if d.Error != "" {
+				log.Printf("Warning: %s %s: %s", d.Type, d.Name, d.Error)
+			}
+		}
+	}
+
+	if c.config.Quiet {
+		c.recordRoot(ctx, res, extra)
+		return
+	}
+
+	fmt.Printf("\nDirectory:         %s\n", res.Directory)
+	fmt.Printf("Phase:             %s\n", c.config.Phase)
+	printBackend(res.Backend)
 	fmt.Printf("Terraform Version: %s\n", c.tfVersion)
 	fmt.Printf("OTEL Endpoint:     %s\n", c.config.OTELEndpoint)
 	fmt.Println()
 
-	if err := parser.EnsureInit(); err != nil {
-		return fmt.Errorf("terraform init failed: %w", err)
+	fmt.Printf("Found %d module(s)\n", len(res.Modules))
+	fmt.Printf("Found %d provider(s)\n\n", len(res.Providers))
+
+	c.recordRoot(ctx, res, extra)
+}
+
+// recordRoot records the metrics of a root and, if State is set, retires the
+// series it published in the previous run that it no longer has.
+func (c *Collector) recordRoot(ctx context.Context, res ScanResult, extra []attribute.KeyValue) {
+	c.recorded = nil
+	c.recordResult(ctx, res, extra)
+	if c.config.State != nil {
+		c.retire(ctx, c.config.State.replace(res.Directory, c.config.Phase, c.recorded))
 	}
+}
 
-	modules, err := parser.ParseModules()
-	if err != nil {
-		return fmt.Errorf("failed to parse modules: %w", err)
+// retire records 0 for series that are no longer published.
+func (c *Collector) retire(ctx context.Context, stale []PublishedSeries) {
+	for _, ps := range stale {
+		if g, ok := c.gauges[ps.Metric]; ok {
+			g.Record(ctx, 0, metric.WithAttributes(ps.attrs()...))
+		}
+	}
+	if len(stale) > 0 && !c.config.Quiet {
+		fmt.Printf("Retired %d stale series\n", len(stale))
 	}
-	fmt.Printf("Found %d module(s)\n", len(modules))
+}
 
-	providers, err := parser.ParseProviders()
-	if err != nil {
-		return fmt.Errorf("failed to parse providers: %w", err)
+// record records value for the named gauge and, if State is set, remembers
+// the series for the current root.
+func (c *Collector) record(ctx context.Context, name string, value int64, attrs []attribute.KeyValue) {
+	c.gauges[name].Record(ctx, value, metric.WithAttributes(attrs...))
+	if c.config.State != nil {
+		c.recorded = append(c.recorded, newPublishedSeries(name, attrs))
 	}
-	fmt.Printf("Found %d provider(s)\n\n", len(providers))
+}
+
+func (c *Collector) recordResult(ctx context.Context, res ScanResult, extra []attribute.KeyValue) {
+	extra = append(slices.Clip(extra), attribute.String("resolution", res.resolution()))
+	c.constraints = dependencyConstraints(res)
 
-	for _, mod := range modules {
-		c.publishDependencyMetric(ctx, "module", mod.Name, mod.Source, mod.Version, backend)
+	for _, mod := range res.Modules {
+		c.publishDependencyMetric(ctx, "module", mod.Name, mod.Source, mod.Version, res.Backend, extra)
 	}
 
-	for _, prov := range providers {
-		c.publishDependencyMetric(ctx, "provider", prov.Name, prov.Source, prov.Version, backend)
+	for _, prov := range res.Providers {
+		c.publishDependencyMetric(ctx, "provider", prov.Name, prov.Source, prov.Version, res.Backend, extra)
 	}
 
-	return nil
+	for _, dep := range res.Outdated {
+		if dep.Error == "" {
+			c.publishVersionLag(ctx, dep, res.Backend, extra)
+		}
+	}
+
+	for _, v := range res.Violations {
+		c.publishViolation(ctx, v, res.Backend, extra)
+	}
+
+	for _, issue := range res.LockIssues {
+		c.publishLockIssue(ctx, issue, res.Backend, extra)
+	}
 }
 
 func backendAttrs(backend *BackendConfig) []attribute.KeyValue {
-	var org, ws string
-	switch backend.Type {
-	case "workspace":
-		org = backend.Organization
-		ws = backend.Workspace
-	case "s3":
-		org = backend.Bucket
-		ws = backend.Key
-	}
+	org, ws := backend.Identity()
 	return []attribute.KeyValue{
 		attribute.String("backend_type", backend.Type),
 		attribute.String("backend_org", org),
@@ -129,7 +299,9 @@ func backendAttrs(backend *BackendConfig) []attribute.KeyValue {
 	}
 }
 
-func (c *Collector) publishDependencyMetric(ctx context.Context, depType, name, source, version string, backend *BackendConfig) {
+// dependencyAttrs returns the label set identifying a dependency. Every
+// dependency metric uses it so that series join on the same labels.
+func (c *Collector) dependencyAttrs(depType, name, source, version string, backend *BackendConfig, extra []attribute.KeyValue) []attribute.KeyValue {
 	attrs := backendAttrs(backend)
 	attrs = append(attrs,
 		attribute.String("phase", c.config.Phase),
@@ -137,65 +309,157 @@ func (c *Collector) publishDependencyMetric(ctx context.Context, depType, name,
 		attribute.String("dependency_name", name),
 		attribute.String("dependency_source", source),
 		attribute.String("dependency_version", version),
+		attribute.String("dependency_constraint", c.constraints[dependencyKey{depType, name, source}]),
 		attribute.String("terraform_version", c.tfVersion),
 	)
-
-	c.gauge.Record(ctx, 1, metric.WithAttributes(attrs...))
-	fmt.Printf("  %s: %s v%s\n", depType, name, version)
+	return append(attrs, extra...)
 }
 
-// ListDependencies parses and prints modules and providers for the given directory.
-func ListDependencies(directory string) error {
-	parser := NewParser(directory)
+// dependencyKey identifies a module or provider within a root.
+type dependencyKey struct {
+	depType, name, source string
+}
 
-	backend, err := parser.ParseBackend()
-	if err != nil {
-		return fmt.Errorf("failed to detect backend: %w", err)
+// dependencyConstraints returns the version constraint of every module and
+// provider of res, published as dependency_constraint. An empty constraint
+// means the dependency is not pinned at all.
+func dependencyConstraints(res ScanResult) map[dependencyKey]string {
+	constraints := make(map[dependencyKey]string, len(res.Modules)+len(res.Providers))
+	for _, m := range res.Modules {
+		constraints[dependencyKey{"module", m.Name, m.Source}] = m.Constraint
+	}
+	for _, p := range res.Providers {
+		constraints[dependencyKey{"provider", p.Name, p.Source}] = p.Constraint()
 	}
+	return constraints
+}
+
+func (c *Collector) publishDependencyMetric(ctx context.Context, depType, name, source, version string, backend *BackendConfig, extra []attribute.KeyValue) {
+	attrs := c.dependencyAttrs(depType, name, source, version, backend, extra)
 
-	fmt.Printf("\nBackend Type:      %s\n", backend.Type)
-	switch backend.Type {
-	case "workspace":
-		fmt.Printf("Organization:      %s\n", backend.Organization)
-		fmt.Printf("Workspace:         %s\n", backend.Workspace)
-	case "s3":
-		fmt.Printf("S3 Bucket:         %s\n", backend.Bucket)
-		fmt.Printf("S3 Key:            %s\n", backend.Key)
+	c.record(ctx, "terraform_dependency_version", 1, attrs)
+	if !c.config.Quiet {
+		fmt.Printf("  %s: %s v%s\n", depType, name, version)
 	}
+}
 
-	if err := parser.EnsureInit(); err != nil {
-		return fmt.Errorf("terraform init failed: %w", err)
+// publishVersionLag records terraform_dependency_latest_version_info and one
+// terraform_dependency_versions_behind point per update type for a dependency
+// whose latest version is known.
+func (c *Collector) publishVersionLag(ctx context.Context, dep OutdatedDependency, backend *BackendConfig, extra []attribute.KeyValue) {
+	attrs := c.dependencyAttrs(dep.Type, dep.Name, dep.Source, dep.Version, backend, extra)
+	attrs = slices.Clip(attrs)
+
+	c.record(ctx, "terraform_dependency_latest_version_info", 1, append(attrs, attribute.String("latest_version", dep.Latest)))
+	for _, lag := range []struct {
+		updateType string
+		count      int
+	}{
+		{"major", dep.Behind.Major},
+		{"minor", dep.Behind.Minor},
+		{"patch", dep.Behind.Patch},
+	} {
+		c.record(ctx, "terraform_dependency_versions_behind", int64(lag.count), append(attrs, attribute.String("update_type", lag.updateType)))
 	}
+}
 
-	modules, err := parser.ParseModules()
-	if err != nil {
-		return fmt.Errorf("failed to parse modules: %w", err)
+// publishViolation records a terraform_policy_violation point with the
+// dependency labels of the offending module, provider, Terraform version or
+// backend plus the rule name and severity.
+func (c *Collector) publishViolation(ctx context.Context, v Violation, backend *BackendConfig, extra []attribute.KeyValue) {
+	attrs := c.dependencyAttrs(v.Type, v.Name, v.Source, v.Version, backend, extra)
+	attrs = append(attrs,
+		attribute.String("rule", v.Rule),
+		attribute.String("severity", v.Severity),
+	)
+	c.record(ctx, "terraform_policy_violation", 1, attrs)
+}
+
+// publishLockIssue records a terraform_lock_issue point with the dependency
+// labels of the provider plus the kind of issue.
+func (c *Collector) publishLockIssue(ctx context.Context, issue LockIssue, backend *BackendConfig, extra []attribute.KeyValue) {
+	attrs := c.dependencyAttrs("provider", issue.Name, issue.Source, issue.Version, backend, extra)
+	c.record(ctx, "terraform_lock_issue", 1, append(attrs, attribute.String("issue", issue.Kind)))
+}
+
+// ListDependencies parses and prints modules and providers for the given
+// directory. Only opts.NoInit applies.
+func ListDependencies(directory string, opts ScanOptions) error {
+	parser := NewParser(directory)
+	parser.noInit = opts.NoInit
+	res := scanDir(parser)
+	if res.Err != nil {
+		return res.Err
 	}
+	printDependencies(res)
+	return nil
+}
 
-	providers, err := parser.ParseProviders()
-	if err != nil {
-		return fmt.Errorf("failed to parse providers: %w", err)
+// ListRoots scans every directory in dirs in parallel according to opts and
+// prints their dependencies in the order of dirs, continuing past roots that fail.
+func ListRoots(ctx context.Context, root string, dirs []string, opts ScanOptions) *ScanSummary {
+	summary := &ScanSummary{}
+	for _, res := range ScanRoots(ctx, dirs, opts) {
+		rel := relativeDir(root, res.Directory)
+		fmt.Printf("\n=== %s ===\n", rel)
+		if res.Err != nil {
+			log.Printf("Error: %s: %v", rel, res.Err)
+			summary.Failed = append(summary.Failed, RootError{Directory: rel, Err: res.Err})
+			continue
+		}
+		printDependencies(res)
+		summary.Scanned = append(summary.Scanned, rel)
 	}
+	return summary
+}
+
+func printDependencies(res ScanResult) {
+	fmt.Println()
+	printBackend(res.Backend)
 
-	if len(modules) > 0 {
+	if len(res.Modules) > 0 {
 		fmt.Println("\nModules:")
-		for _, m := range modules {
-			fmt.Printf("  %-30s %s @ %s\n", m.Name, m.Source, m.Version)
+		if res.Resolution == ResolutionStatic {
+			fmt.Println("  (not resolved: read from module blocks without terraform init)")
+		}
+		for _, m := range res.Modules {
+			fmt.Printf("  %-30s %s @ %s%s\n", m.Name, m.Source, m.Version, declaration(m.Constraint, m.Location))
 		}
 	}
 
-	if len(providers) > 0 {
+	if len(res.Providers) > 0 {
 		fmt.Println("\nProviders:")
-		for _, p := range providers {
-			fmt.Printf("  %-30s %s @ %s\n", p.Name, p.Source, p.Version)
+		for _, p := range res.Providers {
+			fmt.Printf("  %-30s %s @ %s%s\n", p.Name, p.Source, p.Version, declaration(p.Constraint(), ""))
 		}
 	}
 
-	if len(modules) == 0 && len(providers) == 0 {
+	if len(res.Modules) == 0 && len(res.Providers) == 0 {
 		fmt.Println("\nNo modules or providers found.")
 	}
 
-	return nil
+	if len(res.LockIssues) > 0 {
+		fmt.Println("\nLock file issues:")
+		for _, issue := range res.LockIssues {
+			fmt.Printf("  %-12s %s\n", issue.Kind, issue)
+		}
+	}
+}
+
+// declaration formats the version constraint and location of a dependency
+// for listings, or returns "" if both are unknown.
+func declaration(constraint, location string) string {
+	var parts []string
+	if constraint != "" {
+		parts = append(parts, "constraint "+constraint)
+	}
+	if location != "" {
+		parts = append(parts, location)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
 }
 
 func getTerraformVersion() string {
@@ -248,13 +248,53 @@ func TestBackendAttrs(t *testing.T) {
 				"backend_workspace": "prod_vpc_terraform.tfstate",
 			},
 		},
+		{
+			name: "gcs",
+			backend: &BackendConfig{
+				Type:   "gcs",
+				Bucket: "gcs-state",
+				Key:    "env_prod",
+			},
+			expected: map[string]string{
+				"backend_type":      "gcs",
+				"backend_org":       "gcs-state",
+				"backend_workspace": "env_prod",
+			},
+		},
+		{
+			name: "azurerm",
+			backend: &BackendConfig{
+				Type:           "azurerm",
+				StorageAccount: "acmetfstate",
+				Container:      "tfstate",
+				Key:            "prod.tfstate",
+			},
+			expected: map[string]string{
+				"backend_type":      "azurerm",
+				"backend_org":       "acmetfstate",
+				"backend_workspace": "tfstate_prod.tfstate",
+			},
+		},
+		{
+			name: "kubernetes",
+			backend: &BackendConfig{
+				Type:         "kubernetes",
+				Namespace:    "terraform",
+				SecretSuffix: "network",
+			},
+			expected: map[string]string{
+				"backend_type":      "kubernetes",
+				"backend_org":       "terraform",
+				"backend_workspace": "network",
+			},
+		},
 		{
 			name: "unknown type",
 			backend: &BackendConfig{
-				Type: "local",
+				Type: "etcd",
 			},
 			expected: map[string]string{
-				"backend_type":      "local",
+				"backend_type":      "etcd",
 				"backend_org":       "",
 				"backend_workspace": "",
 			},
@@ -336,6 +376,28 @@ provider "registry.terraform.io/hashicorp/aws" {
 				"aws",
 			},
 		},
+		{
+			name: "gcs backend",
+			setup: func(t *testing.T) string {
+				t.Helper()
+				dir := t.TempDir()
+				os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+terraform {
+  backend "gcs" {
+    bucket = "gcs-state"
+    prefix = "env/prod"
+  }
+}
+`), 0o644)
+				os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(""), 0o644)
+				return dir
+			},
+			checks: []string{
+				"Backend Type:      gcs",
+				"GCS Bucket:        gcs-state",
+				"GCS Prefix:        env_prod",
+			},
+		},
 		{
 			name: "no dependencies",
 			setup: func(t *testing.T) string {
@@ -402,7 +464,7 @@ terraform {
 			var output string
 			var err error
 			output = captureStdout(func() {
-				err = ListDependencies(dir)
+				err = ListDependencies(dir, ScanOptions{})
 			})
 
 			if tt.wantErr {
@@ -422,3 +484,312 @@ terraform {
 		})
 	}
 }
+
+func TestCollector_CollectRoots(t *testing.T) {
+	root := t.TempDir()
+
+	good := filepath.Join(root, "prod", "network")
+	os.MkdirAll(good, 0o755)
+	os.WriteFile(filepath.Join(good, "main.tf"), []byte(`
+terraform {
+  backend "s3" {
+    bucket = "test-bucket"
+    key    = "network.tfstate"
+  }
+}
+`), 0o644)
+	os.WriteFile(filepath.Join(good, ".terraform.lock.hcl"), []byte(`
+provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.75.1"
+}
+`), 0o644)
+
+	broken := filepath.Join(root, "prod", "broken")
+	os.MkdirAll(broken, 0o755)
+	os.WriteFile(filepath.Join(broken, "main.tf"), []byte(`terraform {}`), 0o644)
+	os.WriteFile(filepath.Join(broken, ".terraform.lock.hcl"), []byte(""), 0o644)
+
+	reader := setupTestMeter(t)
+	collector := NewCollector(CollectorConfig{Directory: root, Phase: "plan"})
+
+	ctx := context.Background()
+	var summary *ScanSummary
+	captureStdout(func() {
+		summary = collector.CollectRoots(ctx, root, []string{broken, good}, ScanOptions{Concurrency: 2})
+	})
+
+	if len(summary.Scanned) != 1 || summary.Scanned[0] != "prod/network" {
+		t.Errorf("expected scanned [prod/network], got %v", summary.Scanned)
+	}
+	if len(summary.Failed) != 1 || summary.Failed[0].Directory != "prod/broken" {
+		t.Fatalf("expected failed [prod/broken], got %v", summary.Failed)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	var points []metricdata.DataPoint[int64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "terraform_dependency_version" {
+				points = m.Data.(metricdata.Gauge[int64]).DataPoints
+			}
+		}
+	}
+	if len(points) != 1 {
+		t.Fatalf("expected 1 data point, got %d", len(points))
+	}
+	dir, ok := points[0].Attributes.Value("directory")
+	if !ok || dir.AsString() != "prod/network" {
+		t.Errorf("expected directory label prod/network, got %q", dir.AsString())
+	}
+}
+
+func TestScanSummary_Print(t *testing.T) {
+	summary := &ScanSummary{
+		Scanned: []string{"prod/network"},
+		Failed:  []RootError{{Directory: "prod/broken", Err: os.ErrNotExist}},
+	}
+	output := captureStdout(summary.Print)
+
+	for _, check := range []string{"Scanned 2 root(s), 1 failed", "FAILED prod/broken"} {
+		if !bytes.Contains([]byte(output), []byte(check)) {
+			t.Errorf("output missing %q", check)
+		}
+	}
+}
+
+func TestCollector_PublishVersionLag(t *testing.T) {
+	reader := setupTestMeter(t)
+	collector := NewCollector(CollectorConfig{
+		Phase: "apply",
+		Quiet: true,
+		Versions: VersionIndex{
+			"registry.terraform.io/hashicorp/aws": {"4.2.1", "4.2.2", "4.2.3", "4.3.0", "5.0.0", "6.0.0"},
+		},
+	})
+
+	res := ScanResult{
+		Backend: &BackendConfig{Type: "s3", Bucket: "state", Key: "network.tfstate"},
+		Providers: []Provider{
+			{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "4.2.1"},
+			{Name: "gone", Source: "registry.terraform.io/acme/gone", Version: "1.0.0"},
+		},
+	}
+	ctx := context.Background()
+	if err := collector.Publish(ctx, res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	points := map[string][]metricdata.DataPoint[int64]{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			points[m.Name] = m.Data.(metricdata.Gauge[int64]).DataPoints
+		}
+	}
+	if n := len(points["terraform_dependency_version"]); n != 2 {
+		t.Fatalf("expected 2 version points, got %d", n)
+	}
+	var versionAttrs attribute.Set
+	for _, dp := range points["terraform_dependency_version"] {
+		if name, _ := dp.Attributes.Value("dependency_name"); name.AsString() == "aws" {
+			versionAttrs = dp.Attributes
+		}
+	}
+
+	// Every label of the version series must be present so the series join.
+	assertJoins := func(metricName string, dp metricdata.DataPoint[int64]) {
+		t.Helper()
+		for _, kv := range versionAttrs.ToSlice() {
+			if v, ok := dp.Attributes.Value(kv.Key); !ok || v != kv.Value {
+				t.Errorf("%s: label %s=%s missing", metricName, kv.Key, kv.Value.Emit())
+			}
+		}
+	}
+
+	behind := map[string]int64{}
+	for _, dp := range points["terraform_dependency_versions_behind"] {
+		assertJoins("terraform_dependency_versions_behind", dp)
+		ut, _ := dp.Attributes.Value("update_type")
+		behind[ut.AsString()] = dp.Value
+	}
+	want := map[string]int64{"major": 2, "minor": 1, "patch": 2}
+	if len(behind) != len(want) {
+		t.Fatalf("expected %v, got %v", want, behind)
+	}
+	for k, v := range want {
+		if behind[k] != v {
+			t.Errorf("expected %s=%d, got %d", k, v, behind[k])
+		}
+	}
+
+	info := points["terraform_dependency_latest_version_info"]
+	if len(info) != 1 {
+		t.Fatalf("expected 1 latest version point (lookup for gone fails), got %d", len(info))
+	}
+	assertJoins("terraform_dependency_latest_version_info", info[0])
+	if latest, _ := info[0].Attributes.Value("latest_version"); latest.AsString() != "6.0.0" || info[0].Value != 1 {
+		t.Errorf("expected latest_version=6.0.0 with value 1, got %q = %d", latest.AsString(), info[0].Value)
+	}
+}
+
+func TestCollector_PublishViolations(t *testing.T) {
+	reader := setupTestMeter(t)
+	collector := NewCollector(CollectorConfig{Phase: "plan", Quiet: true})
+
+	res := ScanResult{
+		Backend:   &BackendConfig{Type: "s3", Bucket: "state", Key: "network.tfstate"},
+		Providers: []Provider{{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.40.0", LockConstraints: ">= 5.0.0"}},
+		Violations: []Violation{
+			{Rule: "aws-minimum", Severity: SeverityError, Type: "provider", Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.40.0", Message: "must satisfy >= 5.60.0"},
+		},
+	}
+	ctx := context.Background()
+	collector.Publish(ctx, res)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	var points []metricdata.DataPoint[int64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "terraform_policy_violation" {
+				points = m.Data.(metricdata.Gauge[int64]).DataPoints
+			}
+		}
+	}
+	if len(points) != 1 {
+		t.Fatalf("expected 1 violation point, got %d", len(points))
+	}
+	assertAttrs(t, points[0].Attributes.ToSlice(), map[string]string{
+		"backend_type":          "s3",
+		"backend_org":           "state",
+		"backend_workspace":     "network.tfstate",
+		"phase":                 "plan",
+		"type":                  "provider",
+		"dependency_name":       "aws",
+		"dependency_source":     "registry.terraform.io/hashicorp/aws",
+		"dependency_version":    "5.40.0",
+		"dependency_constraint": ">= 5.0.0",
+		"terraform_version":     collector.tfVersion,
+		"resolution":            "init",
+		"rule":                  "aws-minimum",
+		"severity":              "error",
+	})
+}
+
+func TestCollector_PublishLockIssues(t *testing.T) {
+	reader := setupTestMeter(t)
+	collector := NewCollector(CollectorConfig{Phase: "plan", Quiet: true})
+
+	res := ScanResult{
+		Backend:   &BackendConfig{Type: "s3", Bucket: "state", Key: "network.tfstate"},
+		Providers: []Provider{{Name: "null", Source: "registry.terraform.io/hashicorp/null", Version: "3.2.3"}},
+		LockIssues: []LockIssue{
+			{Kind: LockUnused, Name: "null", Source: "registry.terraform.io/hashicorp/null", Version: "3.2.3"},
+			{Kind: LockMissing, Name: "tls", Source: "registry.terraform.io/hashicorp/tls", Location: "main.tf:3"},
+		},
+	}
+	ctx := context.Background()
+	collector.Publish(ctx, res)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+
+	issues := map[string]attribute.Set{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "terraform_lock_issue" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Gauge[int64]).DataPoints {
+				name, _ := dp.Attributes.Value("dependency_name")
+				issues[name.AsString()] = dp.Attributes
+			}
+		}
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 lock issue points, got %d", len(issues))
+	}
+	tls := issues["tls"]
+	assertAttrs(t, tls.ToSlice(), map[string]string{
+		"backend_type":          "s3",
+		"backend_org":           "state",
+		"backend_workspace":     "network.tfstate",
+		"phase":                 "plan",
+		"type":                  "provider",
+		"dependency_name":       "tls",
+		"dependency_source":     "registry.terraform.io/hashicorp/tls",
+		"dependency_version":    "",
+		"dependency_constraint": "",
+		"terraform_version":     collector.tfVersion,
+		"resolution":            "init",
+		"issue":                 "missing",
+	})
+	null := issues["null"]
+	if issue, _ := null.Value("issue"); issue.AsString() != "unused" {
+		t.Errorf("expected null to be unused, got %q", issue.AsString())
+	}
+}
+
+func TestCollector_RetireStaleSeries(t *testing.T) {
+	reader := setupTestMeter(t)
+	ctx := context.Background()
+	base := t.TempDir()
+	network := filepath.Join(base, "network")
+	dns := filepath.Join(base, "dns")
+	backend := &BackendConfig{Type: "s3", Bucket: "state", Key: "network.tfstate"}
+	state := NewSeriesState()
+
+	// First run: two roots, aws 5.0.0 in network.
+	first := NewCollector(CollectorConfig{Phase: "apply", Quiet: true, State: state})
+	first.PublishRoots(ctx, base, []ScanResult{
+		{Directory: network, Backend: backend, Providers: []Provider{{Name: "aws", Source: "hashicorp/aws", Version: "5.0.0"}}},
+		{Directory: dns, Backend: backend, Providers: []Provider{{Name: "cloudflare", Source: "cloudflare/cloudflare", Version: "4.0.0"}}},
+	})
+
+	// Second run: aws upgraded and the dns root deleted.
+	second := NewCollector(CollectorConfig{Phase: "apply", Quiet: true, State: state})
+	second.PublishRoots(ctx, base, []ScanResult{
+		{Directory: network, Backend: backend, Providers: []Provider{{Name: "aws", Source: "hashicorp/aws", Version: "5.1.0"}}},
+	})
+	second.RetireMissingRoots(ctx, base, []string{network})
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	got := map[string]int64{}
+	for _, dp := range rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Gauge[int64]).DataPoints {
+		name, _ := dp.Attributes.Value("dependency_name")
+		version, _ := dp.Attributes.Value("dependency_version")
+		got[name.AsString()+"@"+version.AsString()] = dp.Value
+	}
+	want := map[string]int64{"aws@5.0.0": 0, "aws@5.1.0": 1, "cloudflare@4.0.0": 0}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("expected %s=%d, got %d", k, v, got[k])
+		}
+	}
+
+	if _, ok := state.Roots[dns]; ok {
+		t.Error("expected deleted root to be removed from the state")
+	}
+	if n := len(state.Roots[network]["apply"]); n != 1 {
+		t.Errorf("expected 1 stored series for network, got %d", n)
+	}
+}
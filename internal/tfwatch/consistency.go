@@ -0,0 +1,233 @@
+package tfwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Kinds of LockIssue.
+const (
+	// LockUnused is a lock file entry that no module requires any more.
+	LockUnused = "unused"
+	// LockMissing is a required provider without a lock file entry.
+	LockMissing = "missing"
+	// LockUnsatisfied is a locked version that a declared constraint rejects.
+	LockUnsatisfied = "unsatisfied"
+)
+
+// LockIssue is a disagreement between .terraform.lock.hcl and the providers
+// the configuration requires. Terraform refuses to plan with a missing or
+// unsatisfied provider until terraform init updates the lock file; an unused
+// entry is harmless but keeps a stale version in reviews and SBOMs.
+type LockIssue struct {
+	Kind       string `json:"kind" yaml:"kind"` // LockUnused, LockMissing or LockUnsatisfied
+	Name       string `json:"name" yaml:"name"`
+	Source     string `json:"source" yaml:"source"`
+	Version    string `json:"version,omitempty" yaml:"version,omitempty"`       // locked version; "" if missing
+	Module     string `json:"module,omitempty" yaml:"module,omitempty"`         // requiring module key; "" for the root module
+	Constraint string `json:"constraint,omitempty" yaml:"constraint,omitempty"` // rejected constraint, if unsatisfied
+	Location   string `json:"location,omitempty" yaml:"location,omitempty"`     // requirement as "file:line", relative to the root
+}
+
+// String describes the issue for listings.
+func (i LockIssue) String() string {
+	switch i.Kind {
+	case LockUnused:
+		return fmt.Sprintf("%s %s is locked but not required", i.Source, i.Version)
+	case LockMissing:
+		return fmt.Sprintf("%s is required (%s) but not locked", i.Source, i.Location)
+	default:
+		return fmt.Sprintf("%s %s does not satisfy %s (%s)", i.Source, i.Version, i.Constraint, i.Location)
+	}
+}
+
+// checkLock compares the lock file entries in providers with the providers
+// the configuration requires. A provider is missing once, at its first
+// requirement, and unsatisfied once per constraint that rejects it. Unused
+// entries are only reported if required is complete.
+func checkLock(providers []Provider, required []RequiredProvider, complete bool) []LockIssue {
+	locked := make(map[string]Provider, len(providers))
+	for _, p := range providers {
+		locked[qualifiedProviderSource(p.Source)] = p
+	}
+
+	issues := []LockIssue{}
+	used := map[string]bool{}
+	for _, r := range required {
+		if strings.HasPrefix(r.Source, builtinProviderPrefix) {
+			continue
+		}
+		p, ok := locked[r.Source]
+		switch {
+		case !ok && !used[r.Source]:
+			issues = append(issues, LockIssue{Kind: LockMissing, Name: path.Base(r.Source), Source: r.Source, Module: r.Module, Location: r.Location})
+		case ok && r.Constraint != "":
+			// Constraints tfwatch cannot parse are left to terraform init.
+			if sat, err := p.Satisfies(r.Constraint); err == nil && !sat {
+				issues = append(issues, LockIssue{
+					Kind:       LockUnsatisfied,
+					Name:       p.Name,
+					Source:     p.Source,
+					Version:    p.Version,
+					Module:     r.Module,
+					Constraint: r.Constraint,
+					Location:   r.Location,
+				})
+			}
+		}
+		used[r.Source] = true
+	}
+
+	if complete {
+		for _, p := range providers {
+			if !used[qualifiedProviderSource(p.Source)] {
+				issues = append(issues, LockIssue{Kind: LockUnused, Name: p.Name, Source: p.Source, Version: p.Version})
+			}
+		}
+	}
+	return issues
+}
+
+// builtinProviderPrefix starts the source of providers built into Terraform,
+// which are never locked.
+const builtinProviderPrefix = "terraform.io/builtin/"
+
+// providerRequirements returns every provider the configuration requires:
+// the required_providers entries of each module and the providers used
+// without one, which imply "hashicorp/<name>". Registry and remote modules
+// are only read once terraform init has installed them, so complete is
+// false if a non-local module could not be read.
+func (p *Parser) providerRequirements() (required []RequiredProvider, complete bool, err error) {
+	cfg, err := p.staticConfig()
+	if err != nil {
+		return nil, false, err
+	}
+
+	dirs := slices.Clone(cfg.dirs)
+	read := make(map[string]bool, len(dirs))
+	for _, md := range dirs {
+		read[md.key] = true
+	}
+	if !p.noInit {
+		for _, md := range p.installedModules() {
+			if !read[md.key] {
+				md.files = p.parseDir(md.dir)
+				dirs = append(dirs, md)
+				read[md.key] = true
+			}
+		}
+	}
+
+	complete = true
+	for _, m := range cfg.modules {
+		if !isLocalSource(m.Source) && !read[m.Name] {
+			complete = false
+		}
+	}
+
+	for _, md := range dirs {
+		declared := p.requiredProviders(md)
+		required = append(required, declared...)
+		required = append(required, p.impliedProviders(md, declared)...)
+	}
+	return required, complete, nil
+}
+
+// installedModules returns the directory of every module terraform init
+// installed, from .terraform/modules/modules.json, without parsing it.
+// Modules whose directory is gone are left out. It is empty if the file is
+// missing; ParseModules has already warned about that.
+func (p *Parser) installedModules() []moduleDir {
+	data, err := os.ReadFile(filepath.Join(p.directory, ".terraform", "modules", "modules.json"))
+	if err != nil {
+		return nil
+	}
+	var mj modulesJSON
+	if err := json.Unmarshal(data, &mj); err != nil {
+		return nil
+	}
+
+	var dirs []moduleDir
+	for _, entry := range mj.Modules {
+		if entry.Key == "" || entry.Dir == "" {
+			continue
+		}
+		dir := entry.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(p.directory, dir)
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, moduleDir{key: entry.Key, dir: dir})
+		}
+	}
+	return dirs
+}
+
+// impliedProviders returns the providers that the files of md use without
+// declaring them in required_providers. As in Terraform, the local name of a
+// provider block, of the provider argument of a resource or data source, or
+// else the prefix of its type ("aws" for aws_instance) implies
+// "hashicorp/<name>". Each provider is returned once, at its first use.
+func (p *Parser) impliedProviders(md moduleDir, declared []RequiredProvider) []RequiredProvider {
+	seen := map[string]bool{"terraform": true} // terraform_remote_state and terraform_data
+	for _, r := range declared {
+		seen[r.Name] = true
+	}
+
+	var implied []RequiredProvider
+	use := func(name string, r hcl.Range) {
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		implied = append(implied, RequiredProvider{
+			Name:     name,
+			Source:   qualifiedProviderSource("hashicorp/" + name),
+			Module:   md.key,
+			Location: p.location(r),
+		})
+	}
+
+	for _, f := range md.files {
+		content, _, _ := f.Body.PartialContent(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{
+			{Type: "provider", LabelNames: []string{"name"}},
+			{Type: "resource", LabelNames: []string{"type", "name"}},
+			{Type: "data", LabelNames: []string{"type", "name"}},
+		}})
+		if content == nil {
+			continue
+		}
+		for _, block := range content.Blocks {
+			if block.Type == "provider" {
+				use(block.Labels[0], block.DefRange)
+				continue
+			}
+			use(resourceProvider(block), block.DefRange)
+		}
+	}
+	return implied
+}
+
+// resourceProvider returns the local name of the provider of a resource or
+// data block: the root of its provider argument, or the prefix of its type.
+func resourceProvider(block *hcl.Block) string {
+	content, _, _ := block.Body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "provider"}},
+	})
+	if content != nil {
+		if attr, ok := content.Attributes["provider"]; ok {
+			if traversal, diag := hcl.AbsTraversalForExpr(attr.Expr); !diag.HasErrors() {
+				return traversal.RootName()
+			}
+		}
+	}
+	name, _, _ := strings.Cut(block.Labels[0], "_")
+	return name
+}
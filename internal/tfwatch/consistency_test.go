@@ -0,0 +1,205 @@
+package tfwatch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckLock(t *testing.T) {
+	const (
+		aws    = "registry.terraform.io/hashicorp/aws"
+		random = "registry.terraform.io/hashicorp/random"
+	)
+	locked := []Provider{
+		{Name: "aws", Source: aws, Version: "5.75.1"},
+		{Name: "random", Source: random, Version: "3.6.0"},
+	}
+
+	tests := []struct {
+		name      string
+		providers []Provider
+		required  []RequiredProvider
+		complete  bool
+		want      []LockIssue
+	}{
+		{
+			name:      "consistent",
+			providers: locked,
+			required: []RequiredProvider{
+				{Name: "aws", Source: aws, Constraint: "~> 5.0", Location: "main.tf:4"},
+				{Name: "random", Source: random, Location: "main.tf:8"},
+			},
+			complete: true,
+			want:     []LockIssue{},
+		},
+		{
+			name:      "unused",
+			providers: locked,
+			required:  []RequiredProvider{{Name: "aws", Source: aws, Location: "main.tf:4"}},
+			complete:  true,
+			want:      []LockIssue{{Kind: LockUnused, Name: "random", Source: random, Version: "3.6.0"}},
+		},
+		{
+			name:      "unused not known while incomplete",
+			providers: locked,
+			required:  []RequiredProvider{{Name: "aws", Source: aws, Location: "main.tf:4"}},
+			want:      []LockIssue{},
+		},
+		{
+			name:      "missing once",
+			providers: locked[:1],
+			required: []RequiredProvider{
+				{Name: "aws", Source: aws, Location: "main.tf:4"},
+				{Name: "random", Source: random, Module: "network", Location: "modules/network/versions.tf:3"},
+				{Name: "random", Source: random, Module: "dns", Location: "modules/dns/versions.tf:3"},
+			},
+			complete: true,
+			want:     []LockIssue{{Kind: LockMissing, Name: "random", Source: random, Module: "network", Location: "modules/network/versions.tf:3"}},
+		},
+		{
+			name:      "unsatisfied",
+			providers: locked[:1],
+			required: []RequiredProvider{
+				{Name: "aws", Source: aws, Constraint: ">= 5.0", Location: "main.tf:4"},
+				{Name: "aws", Source: aws, Module: "legacy", Constraint: "~> 4.67", Location: "modules/legacy/main.tf:5"},
+				{Name: "aws", Source: aws, Module: "odd", Constraint: "latest", Location: "modules/odd/main.tf:5"},
+			},
+			complete: true,
+			want: []LockIssue{{
+				Kind: LockUnsatisfied, Name: "aws", Source: aws, Version: "5.75.1",
+				Module: "legacy", Constraint: "~> 4.67", Location: "modules/legacy/main.tf:5",
+			}},
+		},
+		{
+			name:     "builtin provider",
+			required: []RequiredProvider{{Name: "terraform", Source: "terraform.io/builtin/terraform", Location: "main.tf:4"}},
+			complete: true,
+			want:     []LockIssue{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkLock(tt.providers, tt.required, tt.complete)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScanDir_LockIssues(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, staticConfig)
+	writeFiles(t, dir, map[string]string{
+		// The network module uses tls without declaring it, and the installed
+		// vpc module requires the locked time provider.
+		"modules/network/certs.tf": `resource "tls_private_key" "ca" {
+  algorithm = "ECDSA"
+}
+
+data "terraform_remote_state" "dns" {
+  backend = "local"
+}
+`,
+		".terraform/modules/vpc/versions.tf": `terraform {
+  required_providers {
+    time = {
+      source = "hashicorp/time"
+    }
+  }
+}
+`,
+		".terraform/modules/modules.json": `{"Modules":[
+			{"Key":"","Source":"","Dir":"."},
+			{"Key":"network","Source":"./modules/network","Dir":"modules/network"},
+			{"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Version":"5.1.2","Dir":".terraform/modules/vpc"},
+			{"Key":"network.subnets","Source":"registry.terraform.io/hashicorp/subnets/cidr","Version":"1.0.0","Dir":".terraform/modules/network.subnets"},
+			{"Key":"tags","Source":"git::https://github.com/acme/terraform-tags.git?ref=v1.2.0","Dir":".terraform/modules/tags"}
+		]}`,
+		".terraform/modules/network.subnets/main.tf": "",
+		".terraform/modules/tags/main.tf":            "",
+		".terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.9.0"
+}
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+}
+provider "registry.terraform.io/hashicorp/null" {
+  version = "3.2.3"
+}
+provider "registry.terraform.io/hashicorp/time" {
+  version = "0.12.1"
+}
+provider "registry.terraform.io/hashicorp/local" {
+  version = "2.5.2"
+}
+`,
+	})
+
+	res := scanDir(NewParser(dir))
+	if res.Err != nil {
+		t.Fatalf("unexpected error: %v", res.Err)
+	}
+	want := []LockIssue{
+		{
+			Kind: LockUnsatisfied, Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.9.0",
+			Module: "network", Constraint: ">= 5.10.0", Location: "modules/network/versions.tf:3",
+		},
+		{Kind: LockMissing, Name: "tls", Source: "registry.terraform.io/hashicorp/tls", Module: "network", Location: "modules/network/certs.tf:1"},
+		{Kind: LockUnused, Name: "local", Source: "registry.terraform.io/hashicorp/local", Version: "2.5.2"},
+	}
+	if !slices.Equal(res.LockIssues, want) {
+		t.Errorf("expected %+v, got %+v", want, res.LockIssues)
+	}
+
+	out := captureStdout(func() { printDependencies(res) })
+	for _, s := range []string{
+		"Lock file issues:",
+		"unsatisfied  registry.terraform.io/hashicorp/aws 5.9.0 does not satisfy >= 5.10.0 (modules/network/versions.tf:3)",
+		"missing      registry.terraform.io/hashicorp/tls is required (modules/network/certs.tf:1) but not locked",
+		"unused       registry.terraform.io/hashicorp/local 2.5.2 is locked but not required",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+}
+
+func TestScanDir_LockIssues_NoInit(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, staticConfig)
+	writeFiles(t, dir, map[string]string{
+		".terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.75.1"
+}
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+}
+provider "registry.terraform.io/hashicorp/null" {
+  version = "3.2.3"
+}
+provider "registry.terraform.io/hashicorp/time" {
+  version = "0.12.1"
+}
+`,
+	})
+	parser := NewParser(dir)
+	parser.noInit = true
+
+	// The registry and Git modules are not read, so the time provider may
+	// be required by one of them.
+	res := scanDir(parser)
+	if len(res.LockIssues) != 0 {
+		t.Errorf("expected no issues, got %+v", res.LockIssues)
+	}
+
+	// Without a lock file there is nothing to check.
+	parser = NewParser(t.TempDir())
+	parser.noInit = true
+	writeFiles(t, parser.directory, staticConfig)
+	if res := scanDir(parser); res.LockIssues != nil {
+		t.Errorf("expected no lock check, got %+v", res.LockIssues)
+	}
+}
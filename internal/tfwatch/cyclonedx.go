@@ -0,0 +1,221 @@
+package tfwatch
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CycloneDXBOM is a CycloneDX 1.5 bill of materials describing the modules
+// and providers of one or more root modules, written by --output cyclonedx.
+type CycloneDXBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []*cdxComponent `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string        `json:"timestamp"`
+	Tools     cdxTools      `json:"tools"`
+	Component *cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []*cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type               string        `json:"type"`
+	BOMRef             string        `json:"bom-ref,omitempty"`
+	Group              string        `json:"group,omitempty"`
+	Name               string        `json:"name"`
+	Version            string        `json:"version,omitempty"`
+	PURL               string        `json:"purl,omitempty"`
+	Hashes             []cdxHash     `json:"hashes,omitempty"`
+	ExternalReferences []cdxExtRef   `json:"externalReferences,omitempty"`
+	Properties         []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxExtRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// NewCycloneDXBOM builds a BOM from scan results. A single root module
+// becomes the metadata component; for multi-root scans the scan root is the
+// metadata component and each root module is an application component
+// depending on its own modules and providers. Dependencies shared by several
+// roots are listed once. Roots that failed to scan are skipped.
+func NewCycloneDXBOM(root string, results []ScanResult, opts ReportOptions) *CycloneDXBOM {
+	opts = opts.withDefaults()
+	bom := &CycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []*cdxComponent{
+				{Type: "application", Name: "tfwatch", Version: opts.ToolVersion},
+			}},
+		},
+		Components:   []*cdxComponent{},
+		Dependencies: []cdxDependency{},
+	}
+
+	byRef := make(map[string]*cdxComponent)
+	addComponent := func(c *cdxComponent) string {
+		if existing, ok := byRef[c.BOMRef]; ok {
+			existing.Hashes = mergeHashes(existing.Hashes, c.Hashes)
+			return c.BOMRef
+		}
+		byRef[c.BOMRef] = c
+		bom.Components = append(bom.Components, c)
+		return c.BOMRef
+	}
+
+	scanned := scannedResults(results)
+	single := isSingleRoot(root, scanned)
+	if !single {
+		bom.Metadata.Component = &cdxComponent{
+			Type:   "application",
+			BOMRef: "root",
+			Name:   dirName(root),
+		}
+	}
+
+	var rootRefs []string
+	for _, res := range scanned {
+		rel := relativeDir(root, res.Directory)
+		rootComp := &cdxComponent{
+			Type:       "application",
+			BOMRef:     "root:" + rel,
+			Name:       dirName(res.Directory),
+			Properties: rootProperties(rel, res, opts),
+		}
+
+		var refs []string
+		for _, pkg := range sbomPackages(res) {
+			refs = append(refs, addComponent(packageComponent(pkg)))
+		}
+		slices.Sort(refs)
+		refs = slices.Compact(refs)
+
+		if single {
+			rootComp.BOMRef = "root"
+			bom.Metadata.Component = rootComp
+		} else {
+			bom.Components = append(bom.Components, rootComp)
+			rootRefs = append(rootRefs, rootComp.BOMRef)
+		}
+		bom.Dependencies = append(bom.Dependencies, cdxDependency{Ref: rootComp.BOMRef, DependsOn: nonNil(refs)})
+	}
+
+	if !single {
+		bom.Dependencies = append([]cdxDependency{{Ref: "root", DependsOn: nonNil(rootRefs)}}, bom.Dependencies...)
+	}
+
+	slices.SortFunc(bom.Components, func(a, b *cdxComponent) int {
+		return strings.Compare(a.BOMRef, b.BOMRef)
+	})
+	for _, c := range bom.Components {
+		if c.Type == "library" {
+			bom.Dependencies = append(bom.Dependencies, cdxDependency{Ref: c.BOMRef, DependsOn: []string{}})
+		}
+	}
+
+	return bom
+}
+
+// Write encodes the BOM as indented JSON.
+func (b *CycloneDXBOM) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// packageComponent converts a dependency to a CycloneDX library component.
+// h1: lock hashes have no CycloneDX algorithm, so they are kept as properties.
+func packageComponent(pkg sbomPackage) *cdxComponent {
+	c := &cdxComponent{
+		Type:    "library",
+		BOMRef:  pkg.Ref,
+		Group:   pkg.Group,
+		Name:    pkg.Package,
+		Version: pkg.Version,
+		PURL:    pkg.PURL,
+		Properties: []cdxProperty{
+			{"tfwatch:dependency_type", pkg.Type},
+			{"tfwatch:dependency_name", pkg.Name},
+			{"tfwatch:dependency_source", pkg.Source},
+		},
+	}
+	if pkg.Location != "" {
+		c.ExternalReferences = []cdxExtRef{{Type: pkg.LocationType, URL: pkg.Location}}
+	}
+	for _, h := range pkg.SHA256 {
+		c.Hashes = append(c.Hashes, cdxHash{Alg: "SHA-256", Content: h})
+	}
+	for _, h := range pkg.LockHashes {
+		c.Properties = append(c.Properties, cdxProperty{"terraform:lock:hash", h})
+	}
+	return c
+}
+
+func rootProperties(rel string, res ScanResult, opts ReportOptions) []cdxProperty {
+	org, ws := res.Backend.Identity()
+	props := []cdxProperty{
+		{"tfwatch:directory", rel},
+		{"tfwatch:backend_type", res.Backend.Type},
+		{"tfwatch:backend_org", org},
+		{"tfwatch:backend_workspace", ws},
+	}
+	if opts.Phase != "" {
+		props = append(props, cdxProperty{"tfwatch:phase", opts.Phase})
+	}
+	if opts.TerraformVersion != "" {
+		props = append(props, cdxProperty{"tfwatch:terraform_version", opts.TerraformVersion})
+	}
+	return props
+}
+
+func mergeHashes(a, b []cdxHash) []cdxHash {
+	for _, h := range b {
+		if !slices.Contains(a, h) {
+			a = append(a, h)
+		}
+	}
+	return a
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
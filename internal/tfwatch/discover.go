@@ -0,0 +1,192 @@
+package tfwatch
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// DiscoverOptions controls which directories DiscoverRoots returns.
+// Patterns are matched against the slash-separated path relative to the
+// scan root; "**" matches any number of path segments.
+type DiscoverOptions struct {
+	Include []string // if set, a root must match at least one pattern
+	Exclude []string // roots (and their subtrees) matching any pattern are skipped
+}
+
+// DiscoverRoots walks root and returns every Terraform root module directory
+// beneath it, in lexical order. A directory is a root module if it contains a
+// .terraform.lock.hcl or a *.tf file with a terraform {} block. .terraform
+// caches and directories referenced as local module sources are skipped, and
+// so are subdirectories that cannot be read, with a warning.
+func DiscoverRoots(root string, opts DiscoverOptions) ([]string, error) {
+	var candidates []string
+	moduleDirs := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if dir == root {
+				return err
+			}
+			// An unreadable directory should not hide the roots beside it.
+			log.Printf("Warning: skipping %s: %v", relativeDir(root, dir), err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if dir != root && (d.Name() == ".terraform" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+
+		rel := relativeDir(root, dir)
+		if dir != root && matchAny(opts.Exclude, rel) {
+			return filepath.SkipDir
+		}
+
+		info := inspectDir(dir)
+		for _, src := range info.localModules {
+			moduleDirs[filepath.Clean(filepath.Join(dir, src))] = true
+		}
+		if info.hasLockFile || info.hasTerraformBlock {
+			candidates = append(candidates, dir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []string
+	for _, dir := range candidates {
+		rel := relativeDir(root, dir)
+		if moduleDirs[filepath.Clean(dir)] && !fileExists(filepath.Join(dir, ".terraform.lock.hcl")) {
+			continue
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			continue
+		}
+		if matchAny(opts.Exclude, rel) {
+			continue
+		}
+		roots = append(roots, dir)
+	}
+
+	return roots, nil
+}
+
+// dirInfo summarizes the Terraform files found directly in one directory.
+type dirInfo struct {
+	hasLockFile       bool
+	hasTerraformBlock bool
+	localModules      []string // module sources starting with ./ or ../
+}
+
+func inspectDir(dir string) dirInfo {
+	info := dirInfo{
+		hasLockFile: fileExists(filepath.Join(dir, ".terraform.lock.hcl")),
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+	parser := hclparse.NewParser()
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		f, diag := parser.ParseHCL(data, file)
+		if diag.HasErrors() {
+			continue
+		}
+
+		content, _, _ := f.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{
+				{Type: "terraform"},
+				{Type: "module", LabelNames: []string{"name"}},
+			},
+		})
+		if content == nil {
+			continue
+		}
+
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "terraform":
+				info.hasTerraformBlock = true
+			case "module":
+				attrs, _ := block.Body.JustAttributes()
+				if src := stringAttr(attrs, "source"); isLocalSource(src) {
+					info.localModules = append(info.localModules, src)
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+// isLocalSource reports whether a module source refers to a local path.
+func isLocalSource(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// relativeDir returns dir relative to root using forward slashes, or "." for root itself.
+func relativeDir(root, dir string) string {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return filepath.ToSlash(dir)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated path against a glob pattern where each
+// segment follows path.Match and a "**" segment matches zero or more segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
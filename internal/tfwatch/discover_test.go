@@ -0,0 +1,163 @@
+package tfwatch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree creates files (relative path → content) under a temp dir.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDiscoverRoots(t *testing.T) {
+	const backend = `terraform {
+  backend "s3" {
+    bucket = "b"
+    key    = "k"
+  }
+}
+`
+	tree := map[string]string{
+		"prod/network/main.tf":                        backend + `module "vpc" { source = "../../modules/vpc" }`,
+		"prod/network/.terraform/modules/vpc/main.tf": backend,
+		"prod/app/.terraform.lock.hcl":                "",
+		"staging/network/versions.tf":                 backend,
+		"legacy/old/main.tf":                          backend,
+		"modules/vpc/versions.tf":                     `terraform { required_version = ">= 1.0" }`,
+		"modules/vpc/main.tf":                         `resource "null_resource" "a" {}`,
+		"scripts/helper.tf":                           `resource "null_resource" "b" {}`,
+		".git/hooks/main.tf":                          backend,
+		"prod/network/.terraform/providers/README.tf": backend,
+		"shared/published-module/.terraform.lock.hcl": "",
+		"shared/published-module/main.tf":             `terraform { required_version = ">= 1.0" }`,
+		"shared/consumer/main.tf":                     backend + `module "m" { source = "../published-module" }`,
+	}
+
+	tests := []struct {
+		name string
+		opts DiscoverOptions
+		want []string
+	}{
+		{
+			name: "all roots",
+			want: []string{
+				"legacy/old",
+				"prod/app",
+				"prod/network",
+				"shared/consumer",
+				"shared/published-module",
+				"staging/network",
+			},
+		},
+		{
+			name: "include",
+			opts: DiscoverOptions{Include: []string{"prod/*"}},
+			want: []string{"prod/app", "prod/network"},
+		},
+		{
+			name: "exclude subtree",
+			opts: DiscoverOptions{Exclude: []string{"legacy/**", "shared"}},
+			want: []string{"prod/app", "prod/network", "staging/network"},
+		},
+		{
+			name: "double star include",
+			opts: DiscoverOptions{Include: []string{"**/network"}},
+			want: []string{"prod/network", "staging/network"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tree)
+			roots, err := DiscoverRoots(root, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, dir := range roots {
+				got = append(got, relativeDir(root, dir))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDiscoverRoots_SingleRoot(t *testing.T) {
+	root := writeTree(t, map[string]string{"main.tf": `terraform {}`})
+	roots, err := DiscoverRoots(root, DiscoverOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(roots) != 1 || roots[0] != root {
+		t.Errorf("expected [%s], got %v", root, roots)
+	}
+}
+
+func TestDiscoverRoots_MissingDir(t *testing.T) {
+	if _, err := DiscoverRoots(filepath.Join(t.TempDir(), "missing"), DiscoverOptions{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestDiscoverRoots_UnreadableDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	root := writeTree(t, map[string]string{
+		"prod/app/main.tf":     `terraform {}`,
+		"secret/vault/main.tf": `terraform {}`,
+	})
+	secret := filepath.Join(root, "secret")
+	if err := os.Chmod(secret, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(secret, 0o755) })
+
+	roots, err := DiscoverRoots(root, DiscoverOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{filepath.Join(root, "prod/app")}; !reflect.DeepEqual(roots, want) {
+		t.Errorf("expected %v, got %v", want, roots)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"prod/*", "prod/app", true},
+		{"prod/*", "prod/app/eu", false},
+		{"prod/**", "prod/app/eu", true},
+		{"prod/**", "prod", true},
+		{"**/network", "network", true},
+		{"**/network", "a/b/network", true},
+		{"**/network", "a/b/network2", false},
+		{"*-eu", "prod-eu", true},
+		{"prod", "prod/app", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
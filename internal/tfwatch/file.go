@@ -0,0 +1,124 @@
+package tfwatch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxPayloadLine bounds one line of a payload file. A recursive scan of a
+// large monorepo produces a few megabytes of OTLP/JSON.
+const maxPayloadLine = 64 << 20
+
+// errInvalidPayload marks a payload file that can never be sent as it is, as
+// opposed to one that could not be read.
+var errInvalidPayload = errors.New("invalid payload")
+
+// fileExporter appends every export to a file as one line of OTLP/JSON: the
+// request body the HTTP/JSON exporter would have sent. It is meant for hosts
+// without a collector; PublishFile sends the file from one that has one.
+type fileExporter struct {
+	path string
+
+	writeMu sync.Mutex // serializes exports
+	baseExporter
+}
+
+// NewFileExporter creates an exporter that appends to path, creating it if
+// needed.
+func NewFileExporter(path string) (sdkmetric.Exporter, error) {
+	if path == "" {
+		return nil, errors.New("OTLP file path is empty")
+	}
+	return &fileExporter{path: path}, nil
+}
+
+// Export appends rm as a single line. The file is opened for each export,
+// so that runs writing to the same file interleave whole lines.
+func (e *fileExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	if err := e.closed(); err != nil {
+		return err
+	}
+
+	data, err := otlpJSON.Marshal(otlpRequest(rm))
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	f, err := os.OpenFile(e.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write metrics to %s: %w", e.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics to %s: %w", e.path, err)
+	}
+	return nil
+}
+
+// PublishFile sends every payload in a file written by a file exporter, in
+// order, and returns how many were delivered. The whole file is parsed
+// before anything is sent, so a damaged file sends nothing. Sending stops at
+// the first failed export; the error names the line to resume from.
+func PublishFile(ctx context.Context, path string, exporter sdkmetric.Exporter) (int, error) {
+	payloads, err := readPayloadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	for i, p := range payloads {
+		for _, rm := range p.metrics {
+			if err := exporter.Export(ctx, rm); err != nil {
+				return i, fmt.Errorf("%s:%d: payload %d of %d not delivered: %w", path, p.line, i+1, len(payloads), err)
+			}
+		}
+	}
+	return len(payloads), nil
+}
+
+// filePayload is one line of a payload file.
+type filePayload struct {
+	line    int
+	metrics []*metricdata.ResourceMetrics
+}
+
+// readPayloadFile parses one OTLP/JSON export request per non-empty line.
+func readPayloadFile(path string) ([]filePayload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var payloads []filePayload
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64<<10), maxPayloadLine)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		req := &colmetricpb.ExportMetricsServiceRequest{}
+		if err := protojson.Unmarshal(sc.Bytes(), req); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w: %w", path, line, errInvalidPayload, err)
+		}
+		payloads = append(payloads, filePayload{line: line, metrics: resourceMetrics(req)})
+	}
+	if err := sc.Err(); errors.Is(err, bufio.ErrTooLong) {
+		return nil, fmt.Errorf("%s: %w: %w", path, errInvalidPayload, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return payloads, nil
+}
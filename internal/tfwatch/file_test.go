@@ -0,0 +1,110 @@
+package tfwatch
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestFileExporter_Publish(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.jsonl")
+	exporter, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := exporter.Export(context.Background(), testResourceMetrics(t)); err != nil {
+			t.Fatalf("Export() error: %v", err)
+		}
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	receiver, sender := spoolReceiver(t, 0)
+	delivered, err := PublishFile(context.Background(), path, sender)
+	if err != nil {
+		t.Fatalf("PublishFile() error: %v", err)
+	}
+	if delivered != 2 {
+		t.Errorf("expected 2 payloads delivered, got %d", delivered)
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(receiver.requests))
+	}
+	// Each line is exactly the request that is published.
+	written := &colmetricpb.ExportMetricsServiceRequest{}
+	if err := protojson.Unmarshal([]byte(lines[0]), written); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(written, receiver.requests[0]) {
+		t.Errorf("published request differs from the written payload")
+	}
+}
+
+func TestPublishFile_Errors(t *testing.T) {
+	valid, err := otlpJSON.Marshal(otlpRequest(testResourceMetrics(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		content       string
+		status        int
+		wantDelivered int
+		wantRequests  int
+		wantErr       string
+	}{
+		{
+			name:    "invalid line",
+			content: string(valid) + "\n\n{not json\n",
+			wantErr: "metrics.jsonl:3: invalid payload",
+		},
+		{
+			name:         "undelivered",
+			content:      string(valid) + "\n" + string(valid) + "\n",
+			status:       http.StatusBadRequest,
+			wantRequests: 1,
+			wantErr:      "metrics.jsonl:1: payload 1 of 2 not delivered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metrics.jsonl")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			receiver, exporter := spoolReceiver(t, tt.status)
+			delivered, err := PublishFile(context.Background(), path, exporter)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if delivered != tt.wantDelivered {
+				t.Errorf("expected %d delivered, got %d", tt.wantDelivered, delivered)
+			}
+			receiver.mu.Lock()
+			defer receiver.mu.Unlock()
+			if len(receiver.paths) != tt.wantRequests {
+				t.Errorf("expected %d requests, got %d", tt.wantRequests, len(receiver.paths))
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package tfwatch
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VersionIndex is an offline list of available versions per registry
+// address, loaded with LoadVersionIndex. It lets latest-version checks run
+// without network access, e.g. from a file mirrored by a separate job.
+type VersionIndex map[string][]string
+
+// versionIndexFile is the on-disk layout of a version index. Keys are
+// provider or module sources in any form accepted in Terraform configuration,
+// e.g. "hashicorp/aws" or "registry.terraform.io/terraform-aws-modules/vpc/aws".
+type versionIndexFile struct {
+	Providers map[string][]string `yaml:"providers"`
+	Modules   map[string][]string `yaml:"modules"`
+}
+
+// LoadVersionIndex reads a version index from a YAML or JSON file.
+func LoadVersionIndex(path string) (VersionIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version index: %w", err)
+	}
+
+	var file versionIndexFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse version index %s: %w", path, err)
+	}
+
+	index := make(VersionIndex, len(file.Providers)+len(file.Modules))
+	for source, versions := range file.Providers {
+		addr, ok := ParseProviderSource(source)
+		if !ok {
+			return nil, fmt.Errorf("version index %s: invalid provider source %q", path, source)
+		}
+		index[addr.String()] = versions
+	}
+	for source, versions := range file.Modules {
+		addr, ok := ParseModuleSource(source)
+		if !ok {
+			return nil, fmt.Errorf("version index %s: invalid module source %q", path, source)
+		}
+		index[addr.String()] = versions
+	}
+	return index, nil
+}
+
+// Versions returns the indexed versions for addr.
+func (idx VersionIndex) Versions(_ context.Context, addr RegistryAddress) ([]string, error) {
+	versions, ok := idx[addr.String()]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in version index", addr)
+	}
+	return versions, nil
+}
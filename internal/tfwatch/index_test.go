@@ -0,0 +1,88 @@
+package tfwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadVersionIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		checks  func(t *testing.T, idx VersionIndex)
+	}{
+		{
+			name: "yaml with short and full sources",
+			content: `
+providers:
+  hashicorp/aws: ["5.75.1", "5.76.0"]
+  registry.opentofu.org/hashicorp/null: ["3.2.3"]
+modules:
+  terraform-aws-modules/vpc/aws: ["5.1.2", "5.2.0"]
+`,
+			checks: func(t *testing.T, idx VersionIndex) {
+				t.Helper()
+				ctx := context.Background()
+				got, err := idx.Versions(ctx, RegistryAddress{Hostname: DefaultRegistryHost, Namespace: "hashicorp", Name: "aws"})
+				if err != nil || !slices.Equal(got, []string{"5.75.1", "5.76.0"}) {
+					t.Errorf("unexpected aws versions: %v, %v", got, err)
+				}
+				if _, err := idx.Versions(ctx, RegistryAddress{Hostname: "registry.opentofu.org", Namespace: "hashicorp", Name: "null"}); err != nil {
+					t.Errorf("expected null on custom host, got %v", err)
+				}
+				mod := RegistryAddress{Hostname: DefaultRegistryHost, Namespace: "terraform-aws-modules", Name: "vpc", System: "aws"}
+				if got, _ := idx.Versions(ctx, mod); len(got) != 2 {
+					t.Errorf("unexpected vpc versions: %v", got)
+				}
+				_, err = idx.Versions(ctx, RegistryAddress{Hostname: DefaultRegistryHost, Namespace: "hashicorp", Name: "google"})
+				if err == nil || !strings.Contains(err.Error(), "not found in version index") {
+					t.Errorf("expected not found error, got %v", err)
+				}
+			},
+		},
+		{
+			name:    "json",
+			content: `{"providers": {"hashicorp/aws": ["5.76.0"]}}`,
+			checks: func(t *testing.T, idx VersionIndex) {
+				t.Helper()
+				if len(idx) != 1 {
+					t.Errorf("expected 1 entry, got %v", idx)
+				}
+			},
+		},
+		{
+			name:    "invalid module source",
+			content: "modules:\n  ./local: [\"1.0.0\"]\n",
+			wantErr: `invalid module source "./local"`,
+		},
+		{
+			name:    "malformed",
+			content: "providers: [",
+			wantErr: "failed to parse version index",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "index.yaml")
+			os.WriteFile(path, []byte(tt.content), 0o644)
+
+			idx, err := LoadVersionIndex(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.checks(t, idx)
+		})
+	}
+}
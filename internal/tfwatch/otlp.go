@@ -0,0 +1,139 @@
+package tfwatch
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/grpc/credentials"
+)
+
+// OTLP protocols accepted by ExporterConfig.Protocol, named as in
+// OTEL_EXPORTER_OTLP_PROTOCOL.
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolHTTPJSON     = "http/json"
+)
+
+// DefaultURLPath is the OTLP/HTTP metrics path used when none is configured.
+const DefaultURLPath = "/v1/metrics"
+
+// ExporterConfig describes where and how metrics are pushed via OTLP.
+type ExporterConfig struct {
+	Protocol    string // ProtocolGRPC (default), ProtocolHTTPProtobuf or ProtocolHTTPJSON
+	Endpoint    string // host:port of the collector
+	Insecure    bool   // plaintext gRPC or http:// instead of TLS
+	URLPath     string // HTTP protocols only; defaults to DefaultURLPath
+	Compression string // "gzip" or "none" (default)
+
+	Headers map[string]string // sent with every export, e.g. API keys
+	Timeout time.Duration     // per export attempt; the exporter default (10s) if zero
+
+	// RetryDeadline bounds how long a failed export is retried with
+	// backoff. Each export is attempted once if it is zero.
+	RetryDeadline time.Duration
+
+	// TLS settings, used unless Insecure is set. Files are reloaded when
+	// they change.
+	CAFile     string // PEM CA bundle to verify the collector; system roots if empty
+	ClientCert string // PEM client certificate for mutual TLS
+	ClientKey  string // PEM key for ClientCert
+	ServerName string // name to verify the collector's certificate against; the endpoint host if empty
+}
+
+// NewExporter creates an OTLP metric exporter for cfg.
+func NewExporter(ctx context.Context, cfg ExporterConfig) (sdkmetric.Exporter, error) {
+	if cfg.URLPath == "" {
+		cfg.URLPath = DefaultURLPath
+	}
+	if !strings.HasPrefix(cfg.URLPath, "/") {
+		cfg.URLPath = "/" + cfg.URLPath
+	}
+	compress := false
+	switch cfg.Compression {
+	case "", "none":
+	case "gzip":
+		compress = true
+	default:
+		return nil, fmt.Errorf("unsupported OTLP compression %q", cfg.Compression)
+	}
+
+	var tlsConf *tls.Config
+	if cfg.Insecure {
+		if cfg.CAFile != "" || cfg.ClientCert != "" || cfg.ClientKey != "" || cfg.ServerName != "" {
+			return nil, errors.New("TLS options require a secure connection")
+		}
+	} else {
+		var err error
+		if tlsConf, err = newTLSConfig(cfg); err != nil {
+			return nil, fmt.Errorf("failed to configure TLS: %w", err)
+		}
+	}
+
+	var (
+		exporter sdkmetric.Exporter
+		err      error
+	)
+	switch cfg.Protocol {
+	case "", ProtocolGRPC:
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		} else {
+			opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tlsConf)))
+		}
+		if compress {
+			opts = append(opts, otlpmetricgrpc.WithCompressor("gzip"))
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, otlpmetricgrpc.WithTimeout(cfg.Timeout))
+		}
+		opts = append(opts, otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig{Enabled: false}))
+		exporter, err = otlpmetricgrpc.New(ctx, opts...)
+
+	case ProtocolHTTPProtobuf:
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(cfg.Endpoint),
+			otlpmetrichttp.WithURLPath(cfg.URLPath),
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		} else {
+			opts = append(opts, otlpmetrichttp.WithTLSClientConfig(tlsConf))
+		}
+		if compress {
+			opts = append(opts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+		}
+		if cfg.Timeout > 0 {
+			opts = append(opts, otlpmetrichttp.WithTimeout(cfg.Timeout))
+		}
+		opts = append(opts, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{Enabled: false}))
+		exporter, err = otlpmetrichttp.New(ctx, opts...)
+
+	case ProtocolHTTPJSON:
+		exporter, err = newJSONExporter(cfg, tlsConf, compress)
+
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", cfg.Protocol)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter: %w", err)
+	}
+	if cfg.RetryDeadline > 0 {
+		exporter = newRetryExporter(exporter, cfg.RetryDeadline)
+	}
+	return exporter, nil
+}
@@ -0,0 +1,241 @@
+package tfwatch
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeReceiver is an in-process OTLP/HTTP metrics endpoint that decodes
+// protobuf and JSON requests.
+type fakeReceiver struct {
+	mu       sync.Mutex
+	requests []*colmetricpb.ExportMetricsServiceRequest
+	paths    []string
+	types    []string
+	encoding []string
+	headers  []http.Header
+	status   int
+}
+
+func (f *fakeReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.Path)
+	f.types = append(f.types, r.Header.Get("Content-Type"))
+	f.encoding = append(f.encoding, r.Header.Get("Content-Encoding"))
+	f.headers = append(f.headers, r.Header.Clone())
+	if f.status != 0 {
+		http.Error(w, "rejected by test", f.status)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := &colmetricpb.ExportMetricsServiceRequest{}
+	if r.Header.Get("Content-Type") == "application/json" {
+		err = protojson.Unmarshal(data, req)
+	} else {
+		err = proto.Unmarshal(data, req)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.requests = append(f.requests, req)
+	w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+	w.WriteHeader(http.StatusOK)
+}
+
+// testResourceMetrics records one dependency gauge point and collects it.
+func testResourceMetrics(t *testing.T) *metricdata.ResourceMetrics {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(attribute.String("service.name", "tfwatch"))),
+	)
+	defer provider.Shutdown(context.Background())
+
+	gauge, err := provider.Meter("tfwatch").Int64Gauge("terraform_dependency_version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gauge.Record(context.Background(), 1, metric.WithAttributes(
+		attribute.String("dependency_name", "aws"),
+		attribute.String("dependency_version", "5.0.0"),
+	))
+
+	rm := &metricdata.ResourceMetrics{}
+	if err := reader.Collect(context.Background(), rm); err != nil {
+		t.Fatal(err)
+	}
+	return rm
+}
+
+func TestNewExporter_HTTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		protocol    string
+		compression string
+		path        string
+		headers     map[string]string
+		status      int
+		wantType    string
+		wantErr     string
+	}{
+		{name: "protobuf", protocol: ProtocolHTTPProtobuf, headers: map[string]string{"Api-Key": "secret"}, wantType: "application/x-protobuf"},
+		{name: "protobuf gzip", protocol: ProtocolHTTPProtobuf, compression: "gzip", path: "/otlp/v1/metrics", wantType: "application/x-protobuf"},
+		{name: "json", protocol: ProtocolHTTPJSON, headers: map[string]string{"Api-Key": "secret"}, wantType: "application/json"},
+		{name: "json gzip", protocol: ProtocolHTTPJSON, compression: "gzip", path: "custom/path", wantType: "application/json"},
+		{name: "json rejected", protocol: ProtocolHTTPJSON, status: http.StatusUnauthorized, wantErr: "401 Unauthorized: rejected by test"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := &fakeReceiver{status: tt.status}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			ctx := context.Background()
+			exporter, err := NewExporter(ctx, ExporterConfig{
+				Protocol:    tt.protocol,
+				Endpoint:    strings.TrimPrefix(server.URL, "http://"),
+				Insecure:    true,
+				URLPath:     tt.path,
+				Compression: tt.compression,
+				Headers:     tt.headers,
+			})
+			if err != nil {
+				t.Fatalf("NewExporter() error: %v", err)
+			}
+			defer exporter.Shutdown(ctx)
+
+			err = exporter.Export(ctx, testResourceMetrics(t))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export() error: %v", err)
+			}
+
+			receiver.mu.Lock()
+			defer receiver.mu.Unlock()
+			if len(receiver.requests) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(receiver.requests))
+			}
+			wantPath := "/" + strings.TrimPrefix(tt.path, "/")
+			if tt.path == "" {
+				wantPath = DefaultURLPath
+			}
+			if receiver.paths[0] != wantPath {
+				t.Errorf("expected path %q, got %q", wantPath, receiver.paths[0])
+			}
+			if receiver.types[0] != tt.wantType {
+				t.Errorf("expected content type %q, got %q", tt.wantType, receiver.types[0])
+			}
+			for k, v := range tt.headers {
+				if got := receiver.headers[0].Get(k); got != v {
+					t.Errorf("expected header %s=%q, got %q", k, v, got)
+				}
+			}
+			if tt.compression == "gzip" && receiver.encoding[0] != "gzip" {
+				t.Errorf("expected gzip encoding, got %q", receiver.encoding[0])
+			}
+
+			rms := receiver.requests[0].ResourceMetrics
+			if len(rms) != 1 || len(rms[0].ScopeMetrics) != 1 || len(rms[0].ScopeMetrics[0].Metrics) != 1 {
+				t.Fatalf("unexpected request: %v", receiver.requests[0])
+			}
+			if attrs := rms[0].Resource.Attributes; len(attrs) != 1 || attrs[0].Value.GetStringValue() != "tfwatch" {
+				t.Errorf("unexpected resource attributes: %v", attrs)
+			}
+			m := rms[0].ScopeMetrics[0].Metrics[0]
+			if m.Name != "terraform_dependency_version" {
+				t.Errorf("unexpected metric %q", m.Name)
+			}
+			points := m.GetGauge().GetDataPoints()
+			if len(points) != 1 || points[0].GetAsInt() != 1 || len(points[0].Attributes) != 2 {
+				t.Fatalf("unexpected data points: %v", points)
+			}
+			if points[0].TimeUnixNano == 0 {
+				t.Error("expected a timestamp")
+			}
+		})
+	}
+}
+
+func TestNewExporter_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     ExporterConfig
+		wantErr string
+	}{
+		{"protocol", ExporterConfig{Protocol: "http", Endpoint: "localhost:4318"}, `unsupported OTLP protocol "http"`},
+		{"compression", ExporterConfig{Compression: "zstd", Endpoint: "localhost:4317"}, `unsupported OTLP compression "zstd"`},
+		{"empty json endpoint", ExporterConfig{Protocol: ProtocolHTTPJSON}, "endpoint is empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExporter(context.Background(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOTLPRequest_JSONEncoding(t *testing.T) {
+	data, err := otlpJSON.Marshal(otlpRequest(testResourceMetrics(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// OTLP/JSON uses lowerCamelCase field names and 64-bit integers as strings.
+	for _, want := range []string{`"resourceMetrics"`, `"scopeMetrics"`, `"dataPoints"`, `"asInt":"1"`, `"timeUnixNano"`} {
+		if !strings.Contains(strings.ReplaceAll(string(data), " ", ""), want) {
+			t.Errorf("expected %s in %s", want, data)
+		}
+	}
+}
+
+func TestResourceMetrics_RoundTrip(t *testing.T) {
+	want := otlpRequest(testResourceMetrics(t))
+	batches := resourceMetrics(want)
+	if len(batches) != 1 {
+		t.Fatalf("expected 1 batch, got %d", len(batches))
+	}
+	if got := otlpRequest(batches[0]); !proto.Equal(got, want) {
+		t.Errorf("round trip changed the request:\ngot  %v\nwant %v", got, want)
+	}
+}
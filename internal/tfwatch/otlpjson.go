@@ -0,0 +1,422 @@
+package tfwatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricpb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// otlpJSON encodes OTLP messages as required by the OTLP/JSON spec: enums
+// as numbers and field names in lowerCamelCase.
+var otlpJSON = protojson.MarshalOptions{UseEnumNumbers: true}
+
+// baseExporter implements what tfwatch's own exporters have in common: the
+// default temporality and aggregation, a no-op ForceFlush, and a Shutdown
+// after which closed reports an error.
+type baseExporter struct {
+	mu       sync.Mutex
+	shutdown bool
+}
+
+func (e *baseExporter) Temporality(k sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(k)
+}
+
+func (e *baseExporter) Aggregation(k sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(k)
+}
+
+// closed returns an error once Shutdown has been called.
+func (e *baseExporter) closed() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.shutdown {
+		return errors.New("exporter is shut down")
+	}
+	return nil
+}
+
+func (e *baseExporter) ForceFlush(context.Context) error { return nil }
+
+func (e *baseExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+// jsonExporter pushes metrics as OTLP/HTTP with JSON bodies. The upstream
+// otlpmetrichttp exporter only speaks protobuf.
+type jsonExporter struct {
+	client  *http.Client
+	url     string
+	gzip    bool
+	headers map[string]string
+
+	baseExporter
+}
+
+func newJSONExporter(cfg ExporterConfig, tlsConf *tls.Config, compress bool) (*jsonExporter, error) {
+	scheme := "https"
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Insecure {
+		scheme = "http"
+	} else {
+		transport.TLSClientConfig = tlsConf
+	}
+	if cfg.Endpoint == "" {
+		return nil, errors.New("OTLP endpoint is empty")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &jsonExporter{
+		client:  &http.Client{Transport: transport, Timeout: timeout},
+		url:     scheme + "://" + cfg.Endpoint + cfg.URLPath,
+		gzip:    compress,
+		headers: cfg.Headers,
+	}, nil
+}
+
+// Export sends rm in a single request.
+func (e *jsonExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if err := e.closed(); err != nil {
+		return err
+	}
+
+	body, err := otlpJSON.Marshal(otlpRequest(rm))
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	if e.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send metrics to %s: %w", e.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("failed to send metrics to %s: %s: %s", e.url, resp.Status, bytes.TrimSpace(msg))
+		if !retryableStatus(resp.StatusCode) {
+			return &permanentError{err}
+		}
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// retryableStatus reports whether an HTTP status is worth retrying, as
+// listed by the OTLP/HTTP spec.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (e *jsonExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return e.baseExporter.Shutdown(ctx)
+}
+
+// otlpRequest converts collected metrics to an OTLP export request. Gauges
+// and sums are converted; tfwatch records no other instrument kinds.
+func otlpRequest(rm *metricdata.ResourceMetrics) *colmetricpb.ExportMetricsServiceRequest {
+	res := &metricpb.ResourceMetrics{Resource: &resourcepb.Resource{}}
+	if rm.Resource != nil {
+		res.Resource.Attributes = otlpAttrs(*rm.Resource.Set())
+		res.SchemaUrl = rm.Resource.SchemaURL()
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		scope := &metricpb.ScopeMetrics{
+			Scope: &commonpb.InstrumentationScope{
+				Name:    sm.Scope.Name,
+				Version: sm.Scope.Version,
+			},
+			SchemaUrl: sm.Scope.SchemaURL,
+		}
+		for _, m := range sm.Metrics {
+			pm := &metricpb.Metric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				pm.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: otlpPoints(data.DataPoints)}}
+			case metricdata.Gauge[float64]:
+				pm.Data = &metricpb.Metric_Gauge{Gauge: &metricpb.Gauge{DataPoints: otlpPoints(data.DataPoints)}}
+			case metricdata.Sum[int64]:
+				pm.Data = &metricpb.Metric_Sum{Sum: otlpSum(data)}
+			case metricdata.Sum[float64]:
+				pm.Data = &metricpb.Metric_Sum{Sum: otlpSum(data)}
+			default:
+				continue
+			}
+			scope.Metrics = append(scope.Metrics, pm)
+		}
+		res.ScopeMetrics = append(res.ScopeMetrics, scope)
+	}
+	return &colmetricpb.ExportMetricsServiceRequest{ResourceMetrics: []*metricpb.ResourceMetrics{res}}
+}
+
+func otlpSum[N int64 | float64](s metricdata.Sum[N]) *metricpb.Sum {
+	temporality := metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	if s.Temporality == metricdata.DeltaTemporality {
+		temporality = metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+	return &metricpb.Sum{
+		DataPoints:             otlpPoints(s.DataPoints),
+		AggregationTemporality: temporality,
+		IsMonotonic:            s.IsMonotonic,
+	}
+}
+
+func otlpPoints[N int64 | float64](points []metricdata.DataPoint[N]) []*metricpb.NumberDataPoint {
+	out := make([]*metricpb.NumberDataPoint, 0, len(points))
+	for _, dp := range points {
+		p := &metricpb.NumberDataPoint{
+			Attributes:   otlpAttrs(dp.Attributes),
+			TimeUnixNano: unixNano(dp.Time),
+		}
+		if !dp.StartTime.IsZero() {
+			p.StartTimeUnixNano = unixNano(dp.StartTime)
+		}
+		switch v := any(dp.Value).(type) {
+		case int64:
+			p.Value = &metricpb.NumberDataPoint_AsInt{AsInt: v}
+		case float64:
+			p.Value = &metricpb.NumberDataPoint_AsDouble{AsDouble: v}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func otlpAttrs(set attribute.Set) []*commonpb.KeyValue {
+	out := make([]*commonpb.KeyValue, 0, set.Len())
+	iter := set.Iter()
+	for iter.Next() {
+		kv := iter.Attribute()
+		out = append(out, &commonpb.KeyValue{Key: string(kv.Key), Value: otlpValue(kv.Value)})
+	}
+	return out
+}
+
+func otlpValue(v attribute.Value) *commonpb.AnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case attribute.INT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case attribute.FLOAT64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case attribute.BOOLSLICE:
+		return otlpArray(v.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return otlpArray(v.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return otlpArray(v.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return otlpArray(v.AsStringSlice(), attribute.StringValue)
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Emit()}}
+}
+
+func otlpArray[T any](items []T, value func(T) attribute.Value) *commonpb.AnyValue {
+	values := make([]*commonpb.AnyValue, len(items))
+	for i, item := range items {
+		values[i] = otlpValue(value(item))
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+}
+
+// resourceMetrics converts an OTLP export request back to the SDK's form, so
+// that a payload written earlier can be sent with any exporter. It is the
+// inverse of otlpRequest: gauges and sums are converted, with their original
+// timestamps; other metric types are skipped.
+func resourceMetrics(req *colmetricpb.ExportMetricsServiceRequest) []*metricdata.ResourceMetrics {
+	out := make([]*metricdata.ResourceMetrics, 0, len(req.ResourceMetrics))
+	for _, prm := range req.ResourceMetrics {
+		rm := &metricdata.ResourceMetrics{
+			Resource: resource.NewWithAttributes(prm.SchemaUrl, attrsFromOTLP(prm.GetResource().GetAttributes())...),
+		}
+		for _, psm := range prm.ScopeMetrics {
+			sm := metricdata.ScopeMetrics{
+				Scope: instrumentation.Scope{
+					Name:      psm.GetScope().GetName(),
+					Version:   psm.GetScope().GetVersion(),
+					SchemaURL: psm.SchemaUrl,
+				},
+			}
+			for _, pm := range psm.Metrics {
+				m := metricdata.Metrics{Name: pm.Name, Description: pm.Description, Unit: pm.Unit}
+				switch data := pm.Data.(type) {
+				case *metricpb.Metric_Gauge:
+					points := data.Gauge.GetDataPoints()
+					if doublePoints(points) {
+						m.Data = metricdata.Gauge[float64]{DataPoints: pointsFromOTLP(points, (*metricpb.NumberDataPoint).GetAsDouble)}
+					} else {
+						m.Data = metricdata.Gauge[int64]{DataPoints: pointsFromOTLP(points, (*metricpb.NumberDataPoint).GetAsInt)}
+					}
+				case *metricpb.Metric_Sum:
+					temporality := metricdata.CumulativeTemporality
+					if data.Sum.AggregationTemporality == metricpb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+						temporality = metricdata.DeltaTemporality
+					}
+					points := data.Sum.GetDataPoints()
+					if doublePoints(points) {
+						m.Data = metricdata.Sum[float64]{
+							DataPoints:  pointsFromOTLP(points, (*metricpb.NumberDataPoint).GetAsDouble),
+							Temporality: temporality,
+							IsMonotonic: data.Sum.IsMonotonic,
+						}
+					} else {
+						m.Data = metricdata.Sum[int64]{
+							DataPoints:  pointsFromOTLP(points, (*metricpb.NumberDataPoint).GetAsInt),
+							Temporality: temporality,
+							IsMonotonic: data.Sum.IsMonotonic,
+						}
+					}
+				default:
+					continue
+				}
+				sm.Metrics = append(sm.Metrics, m)
+			}
+			rm.ScopeMetrics = append(rm.ScopeMetrics, sm)
+		}
+		out = append(out, rm)
+	}
+	return out
+}
+
+// doublePoints reports whether any of points holds a floating-point value.
+func doublePoints(points []*metricpb.NumberDataPoint) bool {
+	for _, p := range points {
+		if _, ok := p.Value.(*metricpb.NumberDataPoint_AsDouble); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func pointsFromOTLP[N int64 | float64](points []*metricpb.NumberDataPoint, value func(*metricpb.NumberDataPoint) N) []metricdata.DataPoint[N] {
+	out := make([]metricdata.DataPoint[N], 0, len(points))
+	for _, p := range points {
+		dp := metricdata.DataPoint[N]{
+			Attributes: attribute.NewSet(attrsFromOTLP(p.Attributes)...),
+			Time:       timeFromUnixNano(p.TimeUnixNano),
+			StartTime:  timeFromUnixNano(p.StartTimeUnixNano),
+			Value:      value(p),
+		}
+		out = append(out, dp)
+	}
+	return out
+}
+
+func timeFromUnixNano(ns uint64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns))
+}
+
+func attrsFromOTLP(kvs []*commonpb.KeyValue) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		out = append(out, attribute.KeyValue{Key: attribute.Key(kv.Key), Value: valueFromOTLP(kv.Value)})
+	}
+	return out
+}
+
+// valueFromOTLP converts an attribute value. Arrays become slices of the
+// type of their first element; nested arrays and maps, which tfwatch never
+// writes, become their JSON text.
+func valueFromOTLP(v *commonpb.AnyValue) attribute.Value {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return attribute.StringValue(v.StringValue)
+	case *commonpb.AnyValue_BoolValue:
+		return attribute.BoolValue(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return attribute.Int64Value(v.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return attribute.Float64Value(v.DoubleValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := v.ArrayValue.GetValues()
+		if len(values) == 0 {
+			return attribute.StringSliceValue(nil)
+		}
+		switch values[0].GetValue().(type) {
+		case *commonpb.AnyValue_BoolValue:
+			return attribute.BoolSliceValue(arrayFromOTLP(values, (*commonpb.AnyValue).GetBoolValue))
+		case *commonpb.AnyValue_IntValue:
+			return attribute.Int64SliceValue(arrayFromOTLP(values, (*commonpb.AnyValue).GetIntValue))
+		case *commonpb.AnyValue_DoubleValue:
+			return attribute.Float64SliceValue(arrayFromOTLP(values, (*commonpb.AnyValue).GetDoubleValue))
+		case *commonpb.AnyValue_StringValue:
+			return attribute.StringSliceValue(arrayFromOTLP(values, (*commonpb.AnyValue).GetStringValue))
+		}
+	case nil:
+		return attribute.StringValue("")
+	}
+	text, _ := otlpJSON.Marshal(v)
+	return attribute.StringValue(string(text))
+}
+
+func arrayFromOTLP[T any](values []*commonpb.AnyValue, get func(*commonpb.AnyValue) T) []T {
+	out := make([]T, len(values))
+	for i, v := range values {
+		out[i] = get(v)
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package tfwatch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/semver"
+)
+
+// VersionLag counts the releases newer than the version in use, grouped by
+// the most significant component that changed: new major versions, new minor
+// versions within the current major, and new patches within the current minor.
+type VersionLag struct {
+	Major int `json:"major" yaml:"major"`
+	Minor int `json:"minor" yaml:"minor"`
+	Patch int `json:"patch" yaml:"patch"`
+}
+
+// UpToDate reports whether no newer release exists.
+func (l VersionLag) UpToDate() bool {
+	return l == VersionLag{}
+}
+
+// String formats the lag for human output, e.g. "1 major, 2 minor, 0 patch".
+func (l VersionLag) String() string {
+	if l.UpToDate() {
+		return "up to date"
+	}
+	return fmt.Sprintf("%d major, %d minor, %d patch", l.Major, l.Minor, l.Patch)
+}
+
+// OutdatedDependency is the result of looking up the latest version of a
+// module or provider.
+type OutdatedDependency struct {
+	Type    string     `json:"type" yaml:"type"` // "module" or "provider"
+	Name    string     `json:"name" yaml:"name"`
+	Source  string     `json:"source" yaml:"source"`
+	Version string     `json:"version" yaml:"version"`
+	Latest  string     `json:"latest,omitempty" yaml:"latest,omitempty"`
+	Behind  VersionLag `json:"behind" yaml:"behind"`
+	Error   string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// VersionSource returns the versions available for a registry address.
+// It is implemented by RegistryClient and VersionIndex.
+type VersionSource interface {
+	Versions(ctx context.Context, addr RegistryAddress) ([]string, error)
+}
+
+// CheckOutdated looks up the latest version of every registry module and
+// provider in results and stores the outcome in each result's Outdated
+// field, which is non-nil afterwards even if there was nothing to look up.
+// Local, VCS and unversioned modules are skipped. Failed lookups are
+// recorded on the dependency rather than failing the root. Lookups run with
+// at most concurrency requests in flight.
+func CheckOutdated(ctx context.Context, registry VersionSource, results []ScanResult, concurrency int) {
+	for i := range results {
+		res := &results[i]
+		if res.Err != nil {
+			continue
+		}
+		res.Outdated = []OutdatedDependency{}
+		for _, m := range res.Modules {
+			if _, ok := ParseModuleSource(m.Source); ok && m.Version != "" {
+				res.Outdated = append(res.Outdated, OutdatedDependency{Type: "module", Name: m.Name, Source: m.Source, Version: m.Version})
+			}
+		}
+		for _, p := range res.Providers {
+			if _, ok := ParseProviderSource(p.Source); ok {
+				res.Outdated = append(res.Outdated, OutdatedDependency{Type: "provider", Name: p.Name, Source: p.Source, Version: p.Version})
+			}
+		}
+	}
+
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range results {
+		for j := range results[i].Outdated {
+			dep := &results[i].Outdated[j]
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				checkLatest(ctx, registry, dep)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func checkLatest(ctx context.Context, registry VersionSource, dep *OutdatedDependency) {
+	addr, _ := ParseProviderSource(dep.Source)
+	if dep.Type == "module" {
+		addr, _ = ParseModuleSource(dep.Source)
+	}
+
+	versions, err := registry.Versions(ctx, addr)
+	if err != nil {
+		dep.Error = err.Error()
+		return
+	}
+	latest, lag, ok := versionLag(dep.Version, versions)
+	if !ok {
+		dep.Error = fmt.Sprintf("no comparable versions for %s", addr)
+		return
+	}
+	dep.Latest, dep.Behind = latest, lag
+}
+
+// versionLag returns the latest stable version in available and how far
+// current is behind it. Prereleases are ignored unless no stable release
+// exists. ok is false if current or every available version is unparsable.
+func versionLag(current string, available []string) (latest string, lag VersionLag, ok bool) {
+	cur, err := semver.Parse(current)
+	if err != nil {
+		return "", VersionLag{}, false
+	}
+
+	var parsed []semver.Version
+	var raw []string
+	for _, s := range available {
+		if v, err := semver.Parse(s); err == nil {
+			parsed = append(parsed, v)
+			raw = append(raw, s)
+		}
+	}
+	stable := func(v semver.Version) bool { return !v.IsPrerelease() }
+	if !slices.ContainsFunc(parsed, stable) {
+		stable = func(semver.Version) bool { return true }
+	}
+
+	best := -1
+	majors := map[uint64]bool{}
+	minors := map[uint64]bool{}
+	patches := map[uint64]bool{}
+	for i, v := range parsed {
+		if !stable(v) {
+			continue
+		}
+		if best < 0 || v.Compare(parsed[best]) > 0 {
+			best = i
+		}
+		if v.Compare(cur) <= 0 {
+			continue
+		}
+		switch {
+		case v.Major > cur.Major:
+			majors[v.Major] = true
+		case v.Major == cur.Major && v.Minor > cur.Minor:
+			minors[v.Minor] = true
+		case v.Major == cur.Major && v.Minor == cur.Minor && v.Patch > cur.Patch:
+			patches[v.Patch] = true
+		}
+	}
+	if best < 0 {
+		return "", VersionLag{}, false
+	}
+
+	return raw[best], VersionLag{Major: len(majors), Minor: len(minors), Patch: len(patches)}, true
+}
+
+// PrintOutdated prints the latest-version table for every root in results,
+// with a "=== dir ===" header per root when there is more than one.
+func PrintOutdated(root string, results []ScanResult) {
+	for _, res := range results {
+		rel := relativeDir(root, res.Directory)
+		if len(results) > 1 {
+			fmt.Printf("\n=== %s ===\n", rel)
+		}
+		if res.Err != nil {
+			log.Printf("Error: %s: %v", rel, res.Err)
+			continue
+		}
+		if len(res.Outdated) == 0 {
+			fmt.Println("\nNo registry modules or providers found.")
+			continue
+		}
+
+		fmt.Printf("\n  %-8s %-30s %-12s %-12s %s\n", "TYPE", "NAME", "CURRENT", "LATEST", "BEHIND")
+		for _, d := range res.Outdated {
+			latest, behind := d.Latest, d.Behind.String()
+			if d.Error != "" {
+				latest, behind = "?", "error: "+d.Error
+			}
+			fmt.Printf("  %-8s %-30s %-12s %-12s %s\n", d.Type, d.Name, d.Version, latest, behind)
+		}
+	}
+}
+
+// OutdatedErrors returns the failed lookups in results, formatted as
+// "dir: type name: error".
+func OutdatedErrors(root string, results []ScanResult) []string {
+	var errs []string
+	for _, res := range results {
+		for _, d := range res.Outdated {
+			if d.Error != "" {
+				errs = append(errs, fmt.Sprintf("%s: %s %s: %s", relativeDir(root, res.Directory), d.Type, d.Name, d.Error))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,146 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVersionLag(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    string
+		available  []string
+		wantLatest string
+		wantLag    VersionLag
+		wantOK     bool
+	}{
+		{
+			name:       "up to date",
+			current:    "5.1.2",
+			available:  []string{"5.1.0", "5.1.2"},
+			wantLatest: "5.1.2",
+			wantOK:     true,
+		},
+		{
+			name:       "behind on every component",
+			current:    "4.2.1",
+			available:  []string{"4.2.1", "4.2.2", "4.2.3", "4.3.0", "4.4.0", "4.4.1", "5.0.0", "5.1.0", "6.0.0"},
+			wantLatest: "6.0.0",
+			wantLag:    VersionLag{Major: 2, Minor: 2, Patch: 2},
+			wantOK:     true,
+		},
+		{
+			name:       "prereleases ignored",
+			current:    "1.0.0",
+			available:  []string{"1.0.0", "1.0.1", "2.0.0-beta1"},
+			wantLatest: "1.0.1",
+			wantLag:    VersionLag{Patch: 1},
+			wantOK:     true,
+		},
+		{
+			name:       "only prereleases",
+			current:    "0.1.0-alpha",
+			available:  []string{"0.1.0-alpha", "0.1.0-beta"},
+			wantLatest: "0.1.0-beta",
+			wantOK:     true,
+		},
+		{
+			name:       "unordered with v prefix",
+			current:    "v1.2.0",
+			available:  []string{"v1.3.0", "v1.2.0", "garbage"},
+			wantLatest: "v1.3.0",
+			wantLag:    VersionLag{Minor: 1},
+			wantOK:     true,
+		},
+		{
+			name:      "unparsable current",
+			current:   "main",
+			available: []string{"1.0.0"},
+		},
+		{
+			name:    "no versions",
+			current: "1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			latest, lag, ok := versionLag(tt.current, tt.available)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if latest != tt.wantLatest {
+				t.Errorf("expected latest %q, got %q", tt.wantLatest, latest)
+			}
+			if lag != tt.wantLag {
+				t.Errorf("expected lag %+v, got %+v", tt.wantLag, lag)
+			}
+		})
+	}
+}
+
+type fakeLister map[string][]string
+
+func (f fakeLister) Versions(_ context.Context, addr RegistryAddress) ([]string, error) {
+	vs, ok := f[addr.String()]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return vs, nil
+}
+
+func TestCheckOutdated(t *testing.T) {
+	results := []ScanResult{
+		{
+			Directory: "/infra/network",
+			Backend:   &BackendConfig{Type: "local"},
+			Modules: []Module{
+				{Name: "vpc", Source: "registry.terraform.io/terraform-aws-modules/vpc/aws", Version: "5.1.2"},
+				{Name: "local", Source: "./modules/local"},
+				{Name: "git", Source: "git::https://example.com/repo.git"},
+			},
+			Providers: []Provider{
+				{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.75.1"},
+				{Name: "gone", Source: "registry.terraform.io/acme/gone", Version: "1.0.0"},
+			},
+		},
+		{Directory: "/infra/broken", Err: errors.New("boom")},
+	}
+	registry := fakeLister{
+		"registry.terraform.io/terraform-aws-modules/vpc/aws": {"5.1.2", "5.2.0"},
+		"registry.terraform.io/hashicorp/aws":                 {"5.75.1"},
+	}
+
+	CheckOutdated(context.Background(), registry, results, 2)
+
+	got := results[0].Outdated
+	if len(got) != 3 {
+		t.Fatalf("expected 3 checked dependencies, got %+v", got)
+	}
+	if got[0].Name != "vpc" || got[0].Latest != "5.2.0" || got[0].Behind != (VersionLag{Minor: 1}) {
+		t.Errorf("unexpected vpc result: %+v", got[0])
+	}
+	if got[1].Name != "aws" || !got[1].Behind.UpToDate() || got[1].Error != "" {
+		t.Errorf("unexpected aws result: %+v", got[1])
+	}
+	if got[2].Name != "gone" || got[2].Error != "not found" {
+		t.Errorf("expected lookup error for gone, got %+v", got[2])
+	}
+	if results[1].Outdated != nil {
+		t.Errorf("expected failed root to be skipped, got %+v", results[1].Outdated)
+	}
+
+	errs := OutdatedErrors("/infra", results)
+	if len(errs) != 1 || errs[0] != "network: provider gone: not found" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	out := captureStdout(func() { PrintOutdated("/infra", results[:1]) })
+	for _, want := range []string{"vpc", "5.2.0", "0 major, 1 minor, 0 patch", "up to date", "error: not found"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
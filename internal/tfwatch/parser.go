@@ -1,6 +1,7 @@
 package tfwatch
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,18 +14,21 @@ import (
 	"github.com/hashicorp/hcl/v2/hclparse"
 )
 
-// BackendConfig describes the detected Terraform backend (cloud or s3).
-type BackendConfig struct {
-	Type         string // "workspace" or "s3"
-	Organization string // cloud backend: tf_org
-	Workspace    string // cloud backend: workspace name
-	Bucket       string // s3 backend: bucket name
-	Key          string // s3 backend: normalized key (slashes → underscores)
-}
-
 // Parser reads Terraform configuration and generated files from a directory.
 type Parser struct {
 	directory string
+
+	// initSem, when set, bounds the number of concurrent terraform init runs.
+	initSem chan struct{}
+	// quietInit buffers terraform init output and only reports it on failure,
+	// so that parallel scans don't interleave their output.
+	quietInit bool
+	// noInit never runs terraform init; modules come from module blocks.
+	noInit bool
+	// tree caches the configuration read by staticConfig.
+	tree *configTree
+
+	warnings []string
 }
 
 // NewParser returns a Parser rooted at the given directory.
@@ -44,6 +48,19 @@ type moduleEntry struct {
 	Dir     string `json:"Dir"`
 }
 
+// Warnings returns the non-fatal problems encountered while parsing, such as
+// missing generated files.
+func (p *Parser) Warnings() []string {
+	return p.warnings
+}
+
+// warnf logs a warning and records it for Warnings.
+func (p *Parser) warnf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("Warning: %s", msg)
+	p.warnings = append(p.warnings, msg)
+}
+
 // needsInit checks whether terraform init has been run by looking for generated files.
 func (p *Parser) needsInit() bool {
 	lockFile := filepath.Join(p.directory, ".terraform.lock.hcl")
@@ -55,12 +72,27 @@ func (p *Parser) needsInit() bool {
 
 // runInit runs terraform init in the configured directory.
 func (p *Parser) runInit() error {
-	fmt.Printf("Running terraform init in %s...\n", p.directory)
+	if p.initSem != nil {
+		p.initSem <- struct{}{}
+		defer func() { <-p.initSem }()
+	}
+
 	cmd := exec.Command("terraform", "init")
 	cmd.Dir = p.directory
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	if !p.quietInit {
+		fmt.Printf("Running terraform init in %s...\n", p.directory)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w\n%s", err, strings.TrimSpace(out.String()))
+	}
+	return nil
 }
 
 // EnsureInit runs terraform init if generated files are missing.
@@ -79,7 +111,7 @@ func (p *Parser) ParseModules() ([]Module, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			log.Printf("Warning: %s not found", path)
+			p.warnf("%s not found", path)
 			return nil, nil
 		}
 		return nil, err
@@ -107,7 +139,8 @@ func (p *Parser) ParseModules() ([]Module, error) {
 }
 
 // ParseProviders reads .terraform.lock.hcl (created by terraform init)
-// and extracts provider source + version using the HCL parser.
+// and extracts provider source, version, constraints and package hashes
+// using the HCL parser.
 // Returns empty slice if the file doesn't exist.
 func (p *Parser) ParseProviders() ([]Provider, error) {
 	path := filepath.Join(p.directory, ".terraform.lock.hcl")
@@ -115,7 +148,7 @@ func (p *Parser) ParseProviders() ([]Provider, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			log.Printf("Warning: %s not found", path)
+			p.warnf("%s not found", path)
 			return nil, nil
 		}
 		return nil, err
@@ -155,9 +188,11 @@ func (p *Parser) ParseProviders() ([]Provider, error) {
 		parts := filepath.Base(source)
 
 		providers = append(providers, Provider{
-			Name:    parts,
-			Source:  source,
-			Version: version,
+			Name:            parts,
+			Source:          source,
+			Version:         version,
+			Hashes:          stringListAttr(attrs, "hashes"),
+			LockConstraints: stringAttr(attrs, "constraints"),
 		})
 	}
 
@@ -165,7 +200,7 @@ func (p *Parser) ParseProviders() ([]Provider, error) {
 }
 
 // ParseBackend scans *.tf files in the directory for terraform {} blocks
-// and auto-detects the backend type (cloud or s3).
+// and auto-detects the backend type (cloud or any built-in backend "<type>").
 func (p *Parser) ParseBackend() (*BackendConfig, error) {
 	files, err := filepath.Glob(filepath.Join(p.directory, "*.tf"))
 	if err != nil {
@@ -198,7 +233,7 @@ func (p *Parser) ParseBackend() (*BackendConfig, error) {
 			if cfg := p.parseCloudBlock(tfBlock); cfg != nil {
 				return cfg, nil
 			}
-			if cfg := p.parseS3Backend(tfBlock); cfg != nil {
+			if cfg := p.parseBackendBlock(tfBlock); cfg != nil {
 				return cfg, nil
 			}
 		}
@@ -206,81 +241,3 @@ func (p *Parser) ParseBackend() (*BackendConfig, error) {
 
 	return nil, fmt.Errorf("no backend configuration found in %s", p.directory)
 }
-
-func (p *Parser) parseCloudBlock(tfBlock *hcl.Block) *BackendConfig {
-	content, _, diag := tfBlock.Body.PartialContent(&hcl.BodySchema{
-		Blocks: []hcl.BlockHeaderSchema{
-			{Type: "cloud"},
-		},
-	})
-	if diag.HasErrors() || len(content.Blocks) == 0 {
-		return nil
-	}
-
-	cloudBlock := content.Blocks[0]
-	attrs, _ := cloudBlock.Body.JustAttributes()
-
-	var org string
-	if v, ok := attrs["organization"]; ok {
-		val, _ := v.Expr.Value(nil)
-		org = val.AsString()
-	}
-
-	// Parse workspaces {} nested block
-	var workspace string
-	wsContent, _, _ := cloudBlock.Body.PartialContent(&hcl.BodySchema{
-		Blocks: []hcl.BlockHeaderSchema{
-			{Type: "workspaces"},
-		},
-	})
-	if wsContent != nil && len(wsContent.Blocks) > 0 {
-		wsAttrs, _ := wsContent.Blocks[0].Body.JustAttributes()
-		if v, ok := wsAttrs["name"]; ok {
-			val, _ := v.Expr.Value(nil)
-			workspace = val.AsString()
-		}
-	}
-
-	return &BackendConfig{
-		Type:         "workspace",
-		Organization: org,
-		Workspace:    workspace,
-	}
-}
-
-func (p *Parser) parseS3Backend(tfBlock *hcl.Block) *BackendConfig {
-	content, _, diag := tfBlock.Body.PartialContent(&hcl.BodySchema{
-		Blocks: []hcl.BlockHeaderSchema{
-			{Type: "backend", LabelNames: []string{"type"}},
-		},
-	})
-	if diag.HasErrors() || len(content.Blocks) == 0 {
-		return nil
-	}
-
-	for _, block := range content.Blocks {
-		if len(block.Labels) == 0 || block.Labels[0] != "s3" {
-			continue
-		}
-
-		attrs, _ := block.Body.JustAttributes()
-
-		var bucket, key string
-		if v, ok := attrs["bucket"]; ok {
-			val, _ := v.Expr.Value(nil)
-			bucket = val.AsString()
-		}
-		if v, ok := attrs["key"]; ok {
-			val, _ := v.Expr.Value(nil)
-			key = strings.ReplaceAll(val.AsString(), "/", "_")
-		}
-
-		return &BackendConfig{
-			Type:   "s3",
-			Bucket: bucket,
-			Key:    key,
-		}
-	}
-
-	return nil
-}
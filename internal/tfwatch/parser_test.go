@@ -3,6 +3,7 @@ package tfwatch
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -129,6 +130,12 @@ func TestParseProviders(t *testing.T) {
 				if providers[1].Version != "3.6.0" {
 					t.Errorf("expected version 3.6.0, got %s", providers[1].Version)
 				}
+				if len(providers[0].Hashes) != 1 || providers[0].Hashes[0] != "h1:abc123=" {
+					t.Errorf("unexpected hashes: %v", providers[0].Hashes)
+				}
+				if providers[0].LockConstraints != ">= 6.28.0" || providers[1].LockConstraints != "" {
+					t.Errorf("unexpected lock constraints: %q, %q", providers[0].LockConstraints, providers[1].LockConstraints)
+				}
 			},
 		},
 		{
@@ -272,8 +279,150 @@ func TestParseBackend(t *testing.T) {
 			},
 		},
 		{
-			name:    "unsupported backend (gcs)",
+			name:    "gcs backend without prefix",
 			fixture: "testdata/backend_gcs.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Type != "gcs" {
+					t.Errorf("expected type gcs, got %s", cfg.Type)
+				}
+				if cfg.Bucket != "my-gcs-bucket" {
+					t.Errorf("expected bucket my-gcs-bucket, got %s", cfg.Bucket)
+				}
+				if cfg.Key != "" {
+					t.Errorf("expected empty prefix, got %q", cfg.Key)
+				}
+			},
+		},
+		{
+			name:    "gcs backend with prefix",
+			fixture: "testdata/backend_gcs_prefix.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Key != "env_prod" {
+					t.Errorf("expected prefix env_prod, got %s", cfg.Key)
+				}
+			},
+		},
+		{
+			name:    "azurerm backend",
+			fixture: "testdata/backend_azurerm.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Type != "azurerm" {
+					t.Errorf("expected type azurerm, got %s", cfg.Type)
+				}
+				if cfg.StorageAccount != "acmetfstate" {
+					t.Errorf("expected storage account acmetfstate, got %s", cfg.StorageAccount)
+				}
+				if cfg.Container != "tfstate" {
+					t.Errorf("expected container tfstate, got %s", cfg.Container)
+				}
+				if cfg.Key != "prod_network.tfstate" {
+					t.Errorf("expected key prod_network.tfstate, got %s", cfg.Key)
+				}
+			},
+		},
+		{
+			name:    "remote backend with workspace prefix",
+			fixture: "testdata/backend_remote.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Type != "remote" {
+					t.Errorf("expected type remote, got %s", cfg.Type)
+				}
+				if cfg.Hostname != "tfe.example.com" {
+					t.Errorf("expected hostname tfe.example.com, got %s", cfg.Hostname)
+				}
+				if cfg.Organization != "acme-corp" {
+					t.Errorf("expected organization acme-corp, got %s", cfg.Organization)
+				}
+				if cfg.Workspace != "networking-" {
+					t.Errorf("expected workspace networking-, got %s", cfg.Workspace)
+				}
+			},
+		},
+		{
+			name:    "http backend strips credentials",
+			fixture: "testdata/backend_http.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Address != "state.example.com" {
+					t.Errorf("expected address state.example.com, got %s", cfg.Address)
+				}
+				if cfg.Path != "v1_states_prod" {
+					t.Errorf("expected path v1_states_prod, got %s", cfg.Path)
+				}
+			},
+		},
+		{
+			name:    "consul backend",
+			fixture: "testdata/backend_consul.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Address != "consul.example.com:8500" {
+					t.Errorf("expected address consul.example.com:8500, got %s", cfg.Address)
+				}
+				if cfg.Path != "terraform_prod_network" {
+					t.Errorf("expected path terraform_prod_network, got %s", cfg.Path)
+				}
+			},
+		},
+		{
+			name:    "pg backend keeps only the host",
+			fixture: "testdata/backend_pg.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Address != "db.example.com" {
+					t.Errorf("expected host db.example.com, got %s", cfg.Address)
+				}
+				if cfg.Schema != "network" {
+					t.Errorf("expected schema network, got %s", cfg.Schema)
+				}
+			},
+		},
+		{
+			name:    "kubernetes backend",
+			fixture: "testdata/backend_kubernetes.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Namespace != "terraform" {
+					t.Errorf("expected namespace terraform, got %s", cfg.Namespace)
+				}
+				if cfg.SecretSuffix != "network" {
+					t.Errorf("expected secret suffix network, got %s", cfg.SecretSuffix)
+				}
+			},
+		},
+		{
+			name:    "local backend",
+			fixture: "testdata/backend_local.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Type != "local" {
+					t.Errorf("expected type local, got %s", cfg.Type)
+				}
+				if cfg.Path != "state_terraform.tfstate" {
+					t.Errorf("expected path state_terraform.tfstate, got %s", cfg.Path)
+				}
+			},
+		},
+		{
+			name:    "oss backend joins prefix and default key",
+			fixture: "testdata/backend_oss.tf",
+			checks: func(t *testing.T, cfg *BackendConfig) {
+				t.Helper()
+				if cfg.Bucket != "acme-oss-state" {
+					t.Errorf("expected bucket acme-oss-state, got %s", cfg.Bucket)
+				}
+				if cfg.Key != "network_terraform.tfstate" {
+					t.Errorf("expected key network_terraform.tfstate, got %s", cfg.Key)
+				}
+			},
+		},
+		{
+			name:    "unsupported backend (artifactory)",
+			fixture: "testdata/backend_unsupported.tf",
 			wantErr: true,
 		},
 		{
@@ -370,3 +519,21 @@ func TestEnsureInit_AlreadyInitialized(t *testing.T) {
 		t.Errorf("EnsureInit() should succeed when already initialized, got: %v", err)
 	}
 }
+
+func TestParserWarnings(t *testing.T) {
+	p := NewParser(t.TempDir())
+	if _, err := p.ParseModules(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.ParseProviders(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	warnings := p.Warnings()
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if !strings.HasSuffix(warnings[0], "modules.json not found") {
+		t.Errorf("unexpected warning: %s", warnings[0])
+	}
+}
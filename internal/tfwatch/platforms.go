@@ -0,0 +1,177 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Hash schemes of .terraform.lock.hcl. An "h1:" hash covers the unpacked
+// package of one platform and is what Terraform records for the packages it
+// installed; a "zh:" hash is the SHA-256 of the release archive of one
+// platform, recorded for every platform the registry signs. Neither says
+// which platform it belongs to.
+const (
+	hashSchemeH1 = "h1"
+	hashSchemeZH = "zh"
+)
+
+// platformPattern matches a Terraform platform such as "linux_amd64".
+var platformPattern = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
+// PlatformCoverage is the result of checking which platforms the lock file
+// hashes of a provider cover.
+type PlatformCoverage struct {
+	Name        string   `json:"name" yaml:"name"`
+	Source      string   `json:"source" yaml:"source"`
+	Version     string   `json:"version" yaml:"version"`
+	H1Hashes    int      `json:"h1_hashes" yaml:"h1_hashes"`
+	ZHHashes    int      `json:"zh_hashes" yaml:"zh_hashes"`
+	Covered     []string `json:"covered" yaml:"covered"`         // required platforms with a matching zh: hash
+	Missing     []string `json:"missing" yaml:"missing"`         // required platforms without one
+	Unpublished []string `json:"unpublished" yaml:"unpublished"` // required platforms the provider has no package for
+	Error       string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ChecksumSource returns the SHA-256 checksum of the release archive of a
+// provider version for a platform. It is implemented by RegistryClient.
+type ChecksumSource interface {
+	PackageChecksum(ctx context.Context, addr RegistryAddress, version, platform string) (string, error)
+}
+
+// CheckPlatforms records in each result's Platforms field which of platforms
+// the lock file covers for every locked registry provider. A platform is
+// covered if the checksum the registry publishes for its archive is one of
+// the provider's zh: hashes, as after "terraform providers lock -platform".
+// A lock file written from a mirror or plugin cache only has h1: hashes and
+// covers none. A platform the registry has no package for is unpublished.
+// Any other failed lookup is recorded on the provider, and its platform is in
+// none of the lists. Lookups run with at most concurrency requests in flight.
+func CheckPlatforms(ctx context.Context, checksums ChecksumSource, results []ScanResult, platforms []string, concurrency int) {
+	for i := range results {
+		res := &results[i]
+		if res.Err != nil {
+			continue
+		}
+		res.Platforms = []PlatformCoverage{}
+		for _, p := range res.Providers {
+			if _, ok := ParseProviderSource(p.Source); !ok {
+				continue
+			}
+			res.Platforms = append(res.Platforms, PlatformCoverage{
+				Name:        p.Name,
+				Source:      p.Source,
+				Version:     p.Version,
+				H1Hashes:    len(hashesWithScheme(p.Hashes, hashSchemeH1)),
+				ZHHashes:    len(hashesWithScheme(p.Hashes, hashSchemeZH)),
+				Covered:     []string{},
+				Missing:     []string{},
+				Unpublished: []string{},
+			})
+		}
+	}
+
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range results {
+		hashes := make(map[string][]string, len(results[i].Providers))
+		for _, p := range results[i].Providers {
+			hashes[p.Source] = p.Hashes
+		}
+		for j := range results[i].Platforms {
+			cov := &results[i].Platforms[j]
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				checkCoverage(ctx, checksums, cov, hashes[cov.Source], platforms)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func checkCoverage(ctx context.Context, checksums ChecksumSource, cov *PlatformCoverage, hashes []string, platforms []string) {
+	addr, _ := ParseProviderSource(cov.Source)
+	zh := hashesWithScheme(hashes, hashSchemeZH)
+	for _, platform := range platforms {
+		sum, err := checksums.PackageChecksum(ctx, addr, cov.Version, platform)
+		switch {
+		case errors.Is(err, ErrNoPackage):
+			cov.Unpublished = append(cov.Unpublished, platform)
+		case err != nil:
+			if cov.Error == "" {
+				cov.Error = fmt.Sprintf("%s: %v", platform, err)
+			}
+		case slices.Contains(zh, sum):
+			cov.Covered = append(cov.Covered, platform)
+		default:
+			cov.Missing = append(cov.Missing, platform)
+		}
+	}
+}
+
+// PlatformErrors returns the number of providers in results whose checksums
+// could not all be looked up.
+func PlatformErrors(results []ScanResult) int {
+	n := 0
+	for _, res := range results {
+		for _, cov := range res.Platforms {
+			if cov.Error != "" {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// PrintPlatforms prints the platform coverage of every provider in results,
+// one line per provider.
+func PrintPlatforms(root string, results []ScanResult) {
+	fmt.Println("\nPlatform coverage:")
+	for _, res := range results {
+		rel := relativeDir(root, res.Directory)
+		for _, cov := range res.Platforms {
+			hashes := fmt.Sprintf("%d h1:, %d zh:", cov.H1Hashes, cov.ZHHashes)
+			fmt.Printf("  %-24s %-50s %-15s %s\n", rel, cov.Source+" "+cov.Version, hashes, coverageDetail(cov))
+		}
+	}
+}
+
+// coverageDetail lists the platforms of cov by outcome for PrintPlatforms.
+func coverageDetail(cov PlatformCoverage) string {
+	var parts []string
+	for _, p := range []struct {
+		label     string
+		platforms []string
+	}{
+		{"covered", cov.Covered},
+		{"missing", cov.Missing},
+		{"not published", cov.Unpublished},
+	} {
+		if len(p.platforms) > 0 {
+			parts = append(parts, p.label+" "+strings.Join(p.platforms, ", "))
+		}
+	}
+	if cov.Error != "" {
+		parts = append(parts, "error: "+cov.Error)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// hashesWithScheme returns the values of the hashes with the given scheme,
+// without the "<scheme>:" prefix.
+func hashesWithScheme(hashes []string, scheme string) []string {
+	var values []string
+	for _, h := range hashes {
+		if s, value, ok := strings.Cut(h, ":"); ok && s == scheme {
+			values = append(values, strings.ToLower(value))
+		}
+	}
+	return values
+}
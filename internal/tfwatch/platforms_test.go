@@ -0,0 +1,102 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeChecksums serves checksums keyed by "source/version/platform"; an
+// empty checksum is a platform without a package.
+type fakeChecksums map[string]string
+
+func (f fakeChecksums) PackageChecksum(_ context.Context, addr RegistryAddress, version, platform string) (string, error) {
+	sum, ok := f[addr.String()+"/"+version+"/"+platform]
+	switch {
+	case !ok:
+		return "", errors.New("connection refused")
+	case sum == "":
+		return "", ErrNoPackage
+	}
+	return sum, nil
+}
+
+func TestCheckPlatforms(t *testing.T) {
+	results := []ScanResult{
+		{
+			Directory: "/infra/network",
+			Providers: []Provider{
+				{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.75.1", Hashes: []string{"h1:aaa=", "zh:1111", "zh:2222"}},
+				// Locked from a mirror: only the h1: hash of the local platform.
+				{Name: "null", Source: "registry.terraform.io/hashicorp/null", Version: "3.2.3", Hashes: []string{"h1:bbb="}},
+				{Name: "gone", Source: "registry.terraform.io/acme/gone", Version: "1.0.0", Hashes: []string{"zh:3333"}},
+			},
+		},
+		{Directory: "/infra/broken", Err: errors.New("boom")},
+	}
+	checksums := fakeChecksums{
+		"registry.terraform.io/hashicorp/aws/5.75.1/linux_amd64":  "1111",
+		"registry.terraform.io/hashicorp/aws/5.75.1/darwin_arm64": "2222",
+		"registry.terraform.io/hashicorp/null/3.2.3/linux_amd64":  "4444",
+		"registry.terraform.io/hashicorp/null/3.2.3/darwin_arm64": "5555",
+		"registry.terraform.io/acme/gone/1.0.0/linux_amd64":       "",
+	}
+
+	CheckPlatforms(context.Background(), checksums, results, []string{"darwin_arm64", "linux_amd64"}, 2)
+
+	want := []PlatformCoverage{
+		{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.75.1", H1Hashes: 1, ZHHashes: 2, Covered: []string{"darwin_arm64", "linux_amd64"}, Missing: []string{}, Unpublished: []string{}},
+		{Name: "null", Source: "registry.terraform.io/hashicorp/null", Version: "3.2.3", H1Hashes: 1, Covered: []string{}, Missing: []string{"darwin_arm64", "linux_amd64"}, Unpublished: []string{}},
+		{Name: "gone", Source: "registry.terraform.io/acme/gone", Version: "1.0.0", ZHHashes: 1, Covered: []string{}, Missing: []string{}, Unpublished: []string{"linux_amd64"}, Error: "darwin_arm64: connection refused"},
+	}
+	got := results[0].Platforms
+	if len(got) != len(want) {
+		t.Fatalf("expected %d providers, got %+v", len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Version != w.Version || g.H1Hashes != w.H1Hashes || g.ZHHashes != w.ZHHashes ||
+			!slices.Equal(g.Covered, w.Covered) || !slices.Equal(g.Missing, w.Missing) || !slices.Equal(g.Unpublished, w.Unpublished) || g.Error != w.Error {
+			t.Errorf("expected %+v, got %+v", w, g)
+		}
+	}
+	if results[1].Platforms != nil {
+		t.Errorf("expected a failed root to be skipped, got %+v", results[1].Platforms)
+	}
+	if n := PlatformErrors(results); n != 1 {
+		t.Errorf("expected 1 provider with lookup errors, got %d", n)
+	}
+
+	out := captureStdout(func() { PrintPlatforms("/infra", results) })
+	for _, s := range []string{
+		"registry.terraform.io/hashicorp/aws 5.75.1",
+		"1 h1:, 2 zh:    covered darwin_arm64, linux_amd64",
+		"missing darwin_arm64, linux_amd64",
+		"not published linux_amd64; error: darwin_arm64: connection refused",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+}
+
+func TestPolicy_Platforms(t *testing.T) {
+	p, err := LoadPolicy(writePolicy(t, `
+rules:
+  - platforms: [linux_amd64, darwin_arm64]
+  - name: windows
+    platforms: [windows_amd64, linux_amd64]
+    severity: warning
+  - provider: hashicorp/aws
+    version: ">= 5.0"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"darwin_arm64", "linux_amd64", "windows_amd64"}
+	if got := p.Platforms(); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,364 @@
+package tfwatch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// Violation severities, in increasing order of strictness.
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Policy is a set of rules evaluated by "tfwatch check", loaded with
+// LoadPolicy. See docs/policy.md for the file format.
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule constrains one subject: a provider, a registry module, the
+// Terraform version, the backend type, or the platforms the lock file
+// covers. Exactly one of Provider, Module, TerraformVersion, BackendTypes
+// and Platforms is set.
+type PolicyRule struct {
+	Name     string `yaml:"name"`
+	Severity string `yaml:"severity"` // "error" (default) or "warning"
+	Message  string `yaml:"message"`  // optional explanation appended to violations
+
+	Provider string `yaml:"provider"` // provider source, e.g. "hashicorp/aws"
+	Module   string `yaml:"module"`   // module source, e.g. "terraform-aws-modules/vpc/aws"
+
+	// Version is the constraint the dependency must satisfy (allowed versions).
+	Version string `yaml:"version"`
+	// Deny lists constraints the dependency must not satisfy; a bare version
+	// such as "5.0.0" bans exactly that release.
+	Deny []string `yaml:"deny"`
+
+	TerraformVersion string   `yaml:"terraform_version"` // constraint on the Terraform CLI version
+	BackendTypes     []string `yaml:"backend_types"`     // allowed backend types
+	Platforms        []string `yaml:"platforms"`         // platforms every locked provider must have a checksum for
+
+	allow semver.Constraints
+	deny  []semver.Constraints
+}
+
+// Violation is a policy rule that a scanned root module does not satisfy.
+type Violation struct {
+	Rule     string `json:"rule" yaml:"rule"`
+	Severity string `json:"severity" yaml:"severity"`
+	Type     string `json:"type" yaml:"type"` // "provider", "module", "terraform" or "backend"
+	Name     string `json:"name" yaml:"name"`
+	Source   string `json:"source,omitempty" yaml:"source,omitempty"`
+	Version  string `json:"version,omitempty" yaml:"version,omitempty"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// LoadPolicy reads and validates a policy file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var policy Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policy %s: %w", path, err)
+	}
+
+	for i := range policy.Rules {
+		if err := policy.Rules[i].compile(i); err != nil {
+			return nil, fmt.Errorf("policy %s: %w", path, err)
+		}
+	}
+	return &policy, nil
+}
+
+func (r *PolicyRule) compile(index int) error {
+	subjects := 0
+	for _, set := range []bool{r.Provider != "", r.Module != "", r.TerraformVersion != "", len(r.BackendTypes) > 0, len(r.Platforms) > 0} {
+		if set {
+			subjects++
+		}
+	}
+
+	if r.Name == "" {
+		r.Name = fmt.Sprintf("rule-%d", index+1)
+	}
+	if subjects != 1 {
+		return fmt.Errorf("rule %s: exactly one of provider, module, terraform_version, backend_types or platforms is required", r.Name)
+	}
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityError
+	case SeverityError, SeverityWarning:
+	default:
+		return fmt.Errorf("rule %s: severity must be %q or %q", r.Name, SeverityError, SeverityWarning)
+	}
+
+	if r.Provider != "" {
+		addr, ok := ParseProviderSource(r.Provider)
+		if !ok {
+			return fmt.Errorf("rule %s: invalid provider source %q", r.Name, r.Provider)
+		}
+		r.Provider = addr.String()
+	}
+	if r.Module != "" {
+		if addr, ok := ParseModuleSource(r.Module); ok {
+			r.Module = addr.String()
+		}
+	}
+
+	for _, platform := range r.Platforms {
+		if !platformPattern.MatchString(platform) {
+			return fmt.Errorf("rule %s: invalid platform %q, expected <os>_<arch> such as linux_amd64", r.Name, platform)
+		}
+	}
+
+	if r.Provider != "" || r.Module != "" {
+		if r.Version == "" && len(r.Deny) == 0 {
+			return fmt.Errorf("rule %s: version or deny is required", r.Name)
+		}
+	} else if r.Version != "" || len(r.Deny) > 0 {
+		return fmt.Errorf("rule %s: version and deny only apply to provider and module rules", r.Name)
+	}
+
+	var err error
+	allow := r.Version
+	if r.TerraformVersion != "" {
+		allow = r.TerraformVersion
+	}
+	if allow != "" {
+		if r.allow, err = semver.ParseConstraints(allow); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+	}
+	for _, d := range r.Deny {
+		cs, err := semver.ParseConstraints(d)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		r.deny = append(r.deny, cs)
+	}
+	return nil
+}
+
+// Evaluate checks a scanned root module against every rule. terraformVersion
+// is the Terraform CLI version the run uses ("unknown" if not detected).
+func (p *Policy) Evaluate(res ScanResult, terraformVersion string) []Violation {
+	var violations []Violation
+	for i := range p.Rules {
+		violations = append(violations, p.Rules[i].evaluate(res, terraformVersion)...)
+	}
+	return violations
+}
+
+func (r *PolicyRule) evaluate(res ScanResult, terraformVersion string) []Violation {
+	switch {
+	case r.Provider != "":
+		var out []Violation
+		for _, prov := range res.Providers {
+			if addr, ok := ParseProviderSource(prov.Source); ok && addr.String() == r.Provider {
+				out = append(out, r.checkVersion("provider", prov.Name, prov.Source, prov.Version)...)
+			}
+		}
+		return out
+
+	case r.Module != "":
+		var out []Violation
+		for _, mod := range res.Modules {
+			source := mod.Source
+			if addr, ok := ParseModuleSource(source); ok {
+				source = addr.String()
+			}
+			if source != r.Module {
+				continue
+			}
+			version := mod.Version
+			if res.resolution() == ResolutionStatic {
+				// Without terraform init only an exact pin tells which
+				// version will be installed; anything else is not checked.
+				var ok bool
+				if version, ok = pinnedVersion(mod.Constraint); !ok {
+					continue
+				}
+			}
+			out = append(out, r.checkVersion("module", mod.Name, mod.Source, version)...)
+		}
+		return out
+
+	case r.TerraformVersion != "":
+		v, err := semver.Parse(terraformVersion)
+		if err != nil {
+			return []Violation{r.violation("terraform", "terraform", "", terraformVersion, "Terraform version could not be determined")}
+		}
+		if !r.allow.Check(v) {
+			return []Violation{r.violation("terraform", "terraform", "", terraformVersion, "must satisfy "+r.allow.String())}
+		}
+
+	case len(r.BackendTypes) > 0:
+		if res.Backend != nil && !slices.Contains(r.BackendTypes, res.Backend.Type) {
+			return []Violation{r.violation("backend", res.Backend.Type, "", "", "backend type must be one of "+strings.Join(r.BackendTypes, ", "))}
+		}
+
+	case len(r.Platforms) > 0:
+		var out []Violation
+		for _, cov := range res.Platforms {
+			if v, ok := r.checkPlatforms(cov); ok {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// checkPlatforms returns a violation if cov lacks a checksum for one of the
+// rule's platforms, or the provider has no package for one. Platforms that
+// could not be looked up are not violations; see PlatformErrors.
+func (r *PolicyRule) checkPlatforms(cov PlatformCoverage) (Violation, bool) {
+	var missing, unpublished []string
+	for _, platform := range r.Platforms {
+		switch {
+		case slices.Contains(cov.Missing, platform):
+			missing = append(missing, platform)
+		case slices.Contains(cov.Unpublished, platform):
+			unpublished = append(unpublished, platform)
+		}
+	}
+	var problems []string
+	if len(missing) > 0 {
+		problems = append(problems, "lock file has no checksum for "+strings.Join(missing, ", "))
+	}
+	if len(unpublished) > 0 {
+		problems = append(problems, "no package is published for "+strings.Join(unpublished, ", "))
+	}
+	if len(problems) == 0 {
+		return Violation{}, false
+	}
+	return r.violation("provider", cov.Name, cov.Source, cov.Version, strings.Join(problems, "; ")), true
+}
+
+// Platforms returns the platforms required by the policy's platforms rules,
+// sorted, for CheckPlatforms.
+func (p *Policy) Platforms() []string {
+	var platforms []string
+	for _, r := range p.Rules {
+		for _, platform := range r.Platforms {
+			if !slices.Contains(platforms, platform) {
+				platforms = append(platforms, platform)
+			}
+		}
+	}
+	slices.Sort(platforms)
+	return platforms
+}
+
+// pinnedVersion returns the version a constraint such as "5.1.2" or
+// "= 5.1.2" allows exclusively.
+func pinnedVersion(constraint string) (string, bool) {
+	v := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(constraint), "="))
+	if _, err := semver.Parse(v); err != nil {
+		return "", false
+	}
+	return v, true
+}
+
+func (r *PolicyRule) checkVersion(depType, name, source, ver string) []Violation {
+	v, err := semver.Parse(ver)
+	if err != nil {
+		return []Violation{r.violation(depType, name, source, ver, fmt.Sprintf("version %q cannot be compared", ver))}
+	}
+
+	var out []Violation
+	if r.allow != nil && !r.allow.Check(v) {
+		out = append(out, r.violation(depType, name, source, ver, "must satisfy "+r.allow.String()))
+	}
+	for i, cs := range r.deny {
+		if cs.Check(v) {
+			out = append(out, r.violation(depType, name, source, ver, "version is denied ("+r.Deny[i]+")"))
+		}
+	}
+	return out
+}
+
+func (r *PolicyRule) violation(depType, name, source, ver, msg string) Violation {
+	if r.Message != "" {
+		msg += ": " + r.Message
+	}
+	return Violation{
+		Rule:     r.Name,
+		Severity: r.Severity,
+		Type:     depType,
+		Name:     name,
+		Source:   source,
+		Version:  ver,
+		Message:  msg,
+	}
+}
+
+// CheckPolicy evaluates policy against every successfully scanned root in
+// results and stores the outcome in each result's Violations field. An empty
+// terraformVersion is detected with "terraform version".
+func CheckPolicy(policy *Policy, results []ScanResult, terraformVersion string) {
+	if terraformVersion == "" {
+		terraformVersion = getTerraformVersion()
+	}
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Violations = nonNil(policy.Evaluate(results[i], terraformVersion))
+		}
+	}
+}
+
+// PolicySummary counts the violations found by CheckPolicy.
+type PolicySummary struct {
+	Errors   int
+	Warnings int
+}
+
+// Summarize counts the violations in results by severity.
+func Summarize(results []ScanResult) PolicySummary {
+	var s PolicySummary
+	for _, res := range results {
+		for _, v := range res.Violations {
+			if v.Severity == SeverityError {
+				s.Errors++
+			} else {
+				s.Warnings++
+			}
+		}
+	}
+	return s
+}
+
+// PrintViolations prints every violation in results, one per line, followed
+// by the number of errors and warnings.
+func PrintViolations(root string, results []ScanResult) {
+	fmt.Println()
+	for _, res := range results {
+		rel := relativeDir(root, res.Directory)
+		if res.Err != nil {
+			log.Printf("Error: %s: %v", rel, res.Err)
+			continue
+		}
+		for _, v := range res.Violations {
+			subject := joinNonEmpty(" ", v.Type, v.Name, v.Version)
+			fmt.Printf("  %-8s %-24s %-40s %s (%s)\n", strings.ToUpper(v.Severity), rel, subject, v.Message, v.Rule)
+		}
+	}
+
+	s := Summarize(results)
+	fmt.Printf("\n%d error(s), %d warning(s)\n", s.Errors, s.Warnings)
+}
@@ -0,0 +1,295 @@
+package tfwatch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(path, []byte(content), 0o644)
+	return path
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		checks  func(t *testing.T, p *Policy)
+	}{
+		{
+			name: "defaults and normalization",
+			content: `
+rules:
+  - provider: hashicorp/aws
+    version: ">= 5.60"
+  - name: vpc-banned
+    module: terraform-aws-modules/vpc/aws
+    deny: ["5.0.0"]
+    severity: warning
+`,
+			checks: func(t *testing.T, p *Policy) {
+				t.Helper()
+				if len(p.Rules) != 2 {
+					t.Fatalf("expected 2 rules, got %d", len(p.Rules))
+				}
+				if r := p.Rules[0]; r.Name != "rule-1" || r.Severity != SeverityError || r.Provider != "registry.terraform.io/hashicorp/aws" {
+					t.Errorf("unexpected first rule: %+v", r)
+				}
+				if r := p.Rules[1]; r.Module != "registry.terraform.io/terraform-aws-modules/vpc/aws" || r.Severity != SeverityWarning {
+					t.Errorf("unexpected second rule: %+v", r)
+				}
+			},
+		},
+		{
+			name:    "empty file",
+			content: "",
+			checks: func(t *testing.T, p *Policy) {
+				t.Helper()
+				if len(p.Rules) != 0 {
+					t.Errorf("expected no rules, got %d", len(p.Rules))
+				}
+			},
+		},
+		{
+			name:    "no subject",
+			content: "rules:\n  - version: \">= 1.0\"\n",
+			wantErr: "exactly one of",
+		},
+		{
+			name:    "two subjects",
+			content: "rules:\n  - provider: hashicorp/aws\n    module: a/b/c\n    version: \">= 1.0\"\n",
+			wantErr: "exactly one of",
+		},
+		{
+			name:    "dependency rule without version",
+			content: "rules:\n  - provider: hashicorp/aws\n",
+			wantErr: "version or deny is required",
+		},
+		{
+			name:    "deny on terraform rule",
+			content: "rules:\n  - terraform_version: \"~> 1.9\"\n    deny: [\"1.9.0\"]\n",
+			wantErr: "only apply to provider and module rules",
+		},
+		{
+			name:    "invalid constraint",
+			content: "rules:\n  - provider: hashicorp/aws\n    version: \">= five\"\n",
+			wantErr: "invalid version constraint",
+		},
+		{
+			name:    "invalid platform",
+			content: "rules:\n  - platforms: [linux_amd64, darwin-arm64]\n",
+			wantErr: `invalid platform "darwin-arm64"`,
+		},
+		{
+			name:    "invalid severity",
+			content: "rules:\n  - provider: hashicorp/aws\n    version: \">= 5.0\"\n    severity: fatal\n",
+			wantErr: "severity must be",
+		},
+		{
+			name:    "unknown field",
+			content: "rules:\n  - provider: hashicorp/aws\n    min_version: \"5.0\"\n",
+			wantErr: "field min_version not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := LoadPolicy(writePolicy(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.checks(t, p)
+		})
+	}
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	res := ScanResult{
+		Directory: "/infra/network",
+		Backend:   &BackendConfig{Type: "local", Path: "terraform.tfstate"},
+		Modules: []Module{
+			{Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.0.0"},
+			{Name: "eks", Source: "registry.terraform.io/terraform-aws-modules/eks/aws", Version: "20.5.0"},
+			{Name: "internal", Source: "git::https://example.com/internal.git?ref=v1"},
+		},
+		Providers: []Provider{
+			{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.40.0"},
+			{Name: "null", Source: "registry.terraform.io/hashicorp/null", Version: "3.2.3"},
+		},
+		Platforms: []PlatformCoverage{
+			{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.40.0", Covered: []string{"linux_amd64"}, Missing: []string{"darwin_arm64"}},
+			{Name: "null", Source: "registry.terraform.io/hashicorp/null", Version: "3.2.3", Covered: []string{"linux_amd64"}, Error: "darwin_arm64: 503 Service Unavailable"},
+			{Name: "legacy", Source: "registry.terraform.io/acme/legacy", Version: "0.1.0", Covered: []string{"linux_amd64"}, Unpublished: []string{"darwin_arm64"}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		policy    string
+		tfVersion string
+		want      []string // "severity type name: message" per violation
+	}{
+		{
+			name:   "minimum provider version",
+			policy: "rules:\n  - provider: hashicorp/aws\n    version: \">= 5.60\"\n",
+			want:   []string{"error provider aws: must satisfy >= 5.60"},
+		},
+		{
+			name:   "satisfied provider version",
+			policy: "rules:\n  - provider: hashicorp/null\n    version: \"~> 3.2\"\n",
+		},
+		{
+			name:   "banned module version",
+			policy: "rules:\n  - module: registry.terraform.io/terraform-aws-modules/vpc/aws\n    deny: [\"5.0.0\", \">= 6.0\"]\n    message: CVE-2024-0001\n",
+			want:   []string{"error module vpc: version is denied (5.0.0): CVE-2024-0001"},
+		},
+		{
+			name:   "allowed range with exclusion",
+			policy: "rules:\n  - module: terraform-aws-modules/eks/aws\n    version: \">= 19.0, < 21.0, != 20.5.0\"\n    severity: warning\n",
+			want:   []string{"warning module eks: must satisfy >= 19.0, < 21.0, != 20.5.0"},
+		},
+		{
+			name:   "module without comparable version",
+			policy: "rules:\n  - module: git::https://example.com/internal.git?ref=v1\n    version: \">= 1.0\"\n",
+			want:   []string{`error module internal: version "" cannot be compared`},
+		},
+		{
+			name:      "terraform version satisfied",
+			policy:    "rules:\n  - terraform_version: \"~> 1.9\"\n",
+			tfVersion: "1.10.2",
+		},
+		{
+			name:      "terraform version violated",
+			policy:    "rules:\n  - terraform_version: \"~> 1.9.0\"\n",
+			tfVersion: "1.10.2",
+			want:      []string{"error terraform terraform: must satisfy ~> 1.9.0"},
+		},
+		{
+			name:      "terraform version unknown",
+			policy:    "rules:\n  - terraform_version: \">= 1.5\"\n",
+			tfVersion: "unknown",
+			want:      []string{"error terraform terraform: Terraform version could not be determined"},
+		},
+		{
+			name:   "backend type",
+			policy: "rules:\n  - backend_types: [s3, workspace]\n    severity: warning\n",
+			want:   []string{"warning backend local: backend type must be one of s3, workspace"},
+		},
+		{
+			name:   "covered platforms",
+			policy: "rules:\n  - platforms: [linux_amd64]\n",
+		},
+		{
+			name:   "missing platforms",
+			policy: "rules:\n  - platforms: [darwin_arm64, linux_amd64]\n",
+			want: []string{
+				"error provider aws: lock file has no checksum for darwin_arm64",
+				"error provider legacy: no package is published for darwin_arm64",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := LoadPolicy(writePolicy(t, tt.policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []string
+			for _, v := range p.Evaluate(res, tt.tfVersion) {
+				got = append(got, v.Severity+" "+v.Type+" "+v.Name+": "+v.Message)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected violations:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestPolicy_Evaluate_Static(t *testing.T) {
+	p, err := LoadPolicy(writePolicy(t, `
+rules:
+  - module: terraform-aws-modules/vpc/aws
+    version: ">= 5.0"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// With --no-init, modules carry their declared constraint but no version.
+	res := ScanResult{
+		Directory:  "/infra",
+		Resolution: ResolutionStatic,
+		Modules: []Module{
+			{Name: "ranged", Source: "terraform-aws-modules/vpc/aws", Constraint: "~> 5.1"},
+			{Name: "unconstrained", Source: "terraform-aws-modules/vpc/aws"},
+			{Name: "pinned", Source: "terraform-aws-modules/vpc/aws", Constraint: "5.1.2"},
+			{Name: "old", Source: "terraform-aws-modules/vpc/aws", Constraint: "= 4.0.0"},
+		},
+	}
+
+	var got []string
+	for _, v := range p.Evaluate(res, "1.9.8") {
+		got = append(got, v.Name+" "+v.Version+": "+v.Message)
+	}
+	want := []string{"old 4.0.0: must satisfy >= 5.0"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	p, err := LoadPolicy(writePolicy(t, `
+rules:
+  - name: aws-minimum
+    provider: hashicorp/aws
+    version: ">= 5.60"
+  - name: null-pinned
+    provider: hashicorp/null
+    version: "= 3.2.2"
+    severity: warning
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := []ScanResult{
+		{
+			Directory: "/infra/a",
+			Backend:   &BackendConfig{Type: "local"},
+			Providers: []Provider{{Name: "aws", Source: "hashicorp/aws", Version: "5.40.0"}},
+		},
+		{
+			Directory: "/infra/b",
+			Backend:   &BackendConfig{Type: "local"},
+			Providers: []Provider{{Name: "null", Source: "hashicorp/null", Version: "3.2.3"}},
+		},
+		{Directory: "/infra/c", Err: os.ErrNotExist},
+	}
+	CheckPolicy(p, results, "1.9.8")
+
+	if s := Summarize(results); s.Errors != 1 || s.Warnings != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %+v", s)
+	}
+	if results[2].Violations != nil {
+		t.Errorf("expected failed root to be skipped, got %v", results[2].Violations)
+	}
+
+	out := captureStdout(func() { PrintViolations("/infra", results) })
+	for _, want := range []string{"ERROR", "provider aws 5.40.0", "(aws-minimum)", "WARNING", "1 error(s), 1 warning(s)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
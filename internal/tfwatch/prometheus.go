@@ -0,0 +1,114 @@
+package tfwatch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// WritePrometheus encodes the gauges in rm in the Prometheus text exposition
+// format (version 0.0.4). Metrics are written in name order and series in
+// label order so that output is stable between scrapes. Metric types other
+// than gauges are skipped; tfwatch does not record any.
+func WritePrometheus(w io.Writer, rm *metricdata.ResourceMetrics) error {
+	var metrics []metricdata.Metrics
+	for _, sm := range rm.ScopeMetrics {
+		metrics = append(metrics, sm.Metrics...)
+	}
+	slices.SortFunc(metrics, func(a, b metricdata.Metrics) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		var lines []string
+		switch data := m.Data.(type) {
+		case metricdata.Gauge[int64]:
+			for _, dp := range data.DataPoints {
+				lines = append(lines, promLine(m.Name, dp.Attributes, strconv.FormatInt(dp.Value, 10)))
+			}
+		case metricdata.Gauge[float64]:
+			for _, dp := range data.DataPoints {
+				lines = append(lines, promLine(m.Name, dp.Attributes, promFloat(dp.Value)))
+			}
+		default:
+			continue
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		slices.Sort(lines)
+
+		if m.Description != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", m.Name, escapeHelp(m.Description))
+		}
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", m.Name)
+		for _, l := range lines {
+			bw.WriteString(l)
+		}
+	}
+	return bw.Flush()
+}
+
+func promLine(name string, attrs attribute.Set, value string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	if attrs.Len() > 0 {
+		b.WriteByte('{')
+		iter := attrs.Iter()
+		for i := 0; iter.Next(); i++ {
+			kv := iter.Attribute()
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(promLabelName(string(kv.Key)))
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(kv.Value.Emit()))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(value)
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// promLabelName replaces characters Prometheus does not allow in label names
+// (e.g. the dots of OTEL attribute keys) with underscores.
+func promLabelName(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+func promFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
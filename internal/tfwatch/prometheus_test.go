@@ -0,0 +1,81 @@
+package tfwatch
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	gauge := func(name, desc string, points ...metricdata.DataPoint[int64]) metricdata.Metrics {
+		return metricdata.Metrics{Name: name, Description: desc, Data: metricdata.Gauge[int64]{DataPoints: points}}
+	}
+	point := func(v int64, kv ...attribute.KeyValue) metricdata.DataPoint[int64] {
+		return metricdata.DataPoint[int64]{Attributes: attribute.NewSet(kv...), Value: v}
+	}
+
+	tests := []struct {
+		name    string
+		metrics []metricdata.Metrics
+		want    string
+	}{
+		{
+			name: "sorted metrics and series",
+			metrics: []metricdata.Metrics{
+				gauge("b_metric", "Second",
+					point(1, attribute.String("name", "vpc")),
+					point(1, attribute.String("name", "eks")),
+				),
+				gauge("a_metric", "First", point(2)),
+			},
+			want: "# HELP a_metric First\n# TYPE a_metric gauge\na_metric 2\n" +
+				"# HELP b_metric Second\n# TYPE b_metric gauge\n" +
+				"b_metric{name=\"eks\"} 1\nb_metric{name=\"vpc\"} 1\n",
+		},
+		{
+			name: "escaping",
+			metrics: []metricdata.Metrics{
+				gauge("m", "Line one\nback\\slash",
+					point(1, attribute.String("service.name", "a\"b\\c\nd")),
+				),
+			},
+			want: "# HELP m Line one\\nback\\\\slash\n# TYPE m gauge\n" +
+				"m{service_name=\"a\\\"b\\\\c\\nd\"} 1\n",
+		},
+		{
+			name: "float values",
+			metrics: []metricdata.Metrics{{
+				Name: "f",
+				Data: metricdata.Gauge[float64]{DataPoints: []metricdata.DataPoint[float64]{
+					{Attributes: attribute.NewSet(attribute.String("k", "inf")), Value: math.Inf(1)},
+					{Attributes: attribute.NewSet(attribute.String("k", "ts")), Value: 1700000000.5},
+				}},
+			}},
+			want: "# TYPE f gauge\nf{k=\"inf\"} +Inf\nf{k=\"ts\"} 1.7000000005e+09\n",
+		},
+		{
+			name: "empty and unsupported metrics skipped",
+			metrics: []metricdata.Metrics{
+				gauge("empty", "No points"),
+				{Name: "sum", Data: metricdata.Sum[int64]{DataPoints: []metricdata.DataPoint[int64]{point(1)}}},
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := &metricdata.ResourceMetrics{ScopeMetrics: []metricdata.ScopeMetrics{{Metrics: tt.metrics}}}
+			var buf bytes.Buffer
+			if err := WritePrometheus(&buf, rm); err != nil {
+				t.Fatalf("WritePrometheus() error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package tfwatch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// DefaultPushgatewayJob is the job label of pushed metrics.
+const DefaultPushgatewayJob = "tfwatch"
+
+// The labels that make up a Pushgateway grouping key besides the job.
+const (
+	labelBackendOrg       = "backend_org"
+	labelBackendWorkspace = "backend_workspace"
+)
+
+// PushgatewayConfig configures a Prometheus Pushgateway exporter.
+type PushgatewayConfig struct {
+	URL           string            // e.g. http://pushgateway:9091
+	Job           string            // job label; defaults to DefaultPushgatewayJob
+	Headers       map[string]string // sent with every request, e.g. Authorization
+	Timeout       time.Duration     // per request; defaults to 10s
+	RetryDeadline time.Duration     // how long failed pushes are retried; 0 disables retries
+}
+
+// pushgatewayExporter pushes metrics to a Prometheus Pushgateway in the text
+// exposition format. Series are grouped by their backend_org and
+// backend_workspace labels, which become the grouping key, and each group is
+// replaced with a PUT: dependencies that are gone from a deployment
+// disappear from its group, while other deployments are left alone.
+type pushgatewayExporter struct {
+	client  *http.Client
+	url     string
+	job     string
+	headers map[string]string
+
+	baseExporter
+}
+
+// NewPushgatewayExporter creates an exporter that pushes to the Pushgateway
+// at cfg.URL.
+func NewPushgatewayExporter(cfg PushgatewayConfig) (sdkmetric.Exporter, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Pushgateway URL %q: expected http(s)://host:port", cfg.URL)
+	}
+	job := cfg.Job
+	if job == "" {
+		job = DefaultPushgatewayJob
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	var exporter sdkmetric.Exporter = &pushgatewayExporter{
+		client:  &http.Client{Timeout: timeout},
+		url:     strings.TrimSuffix(cfg.URL, "/"),
+		job:     job,
+		headers: cfg.Headers,
+	}
+	if cfg.RetryDeadline > 0 {
+		exporter = newRetryExporter(exporter, cfg.RetryDeadline)
+	}
+	return exporter, nil
+}
+
+// Export pushes every group in rm, in grouping key order, and stops at the
+// first failure. Pushing a group again replaces it, so a retried export
+// does not duplicate anything.
+func (e *pushgatewayExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if err := e.closed(); err != nil {
+		return err
+	}
+
+	for _, g := range groupByBackend(rm) {
+		var body bytes.Buffer
+		if err := WritePrometheus(&body, g.metrics); err != nil {
+			return fmt.Errorf("failed to encode metrics: %w", err)
+		}
+		if err := e.push(ctx, e.groupURL(g.key), &body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *pushgatewayExporter) push(ctx context.Context, target string, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics to %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("failed to push metrics to %s: %s: %s", target, resp.Status, bytes.TrimSpace(msg))
+		if !retryableStatus(resp.StatusCode) {
+			return &permanentError{err}
+		}
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// groupURL returns the Pushgateway URL of a group.
+func (e *pushgatewayExporter) groupURL(key pushGroupKey) string {
+	return e.url + "/metrics/" + pushLabel("job", e.job) +
+		"/" + pushLabel(labelBackendOrg, key.org) +
+		"/" + pushLabel(labelBackendWorkspace, key.workspace)
+}
+
+// pushLabel encodes a grouping key label as a URL path segment pair. Values
+// the path cannot carry as is, slashes in particular (e.g. an S3 key), and
+// empty values use the base64url form the Pushgateway provides for them.
+func pushLabel(name, value string) string {
+	if value == "" {
+		return name + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + url.PathEscape(value)
+}
+
+func (e *pushgatewayExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return e.baseExporter.Shutdown(ctx)
+}
+
+type pushGroupKey struct {
+	org, workspace string
+}
+
+type pushGroup struct {
+	key     pushGroupKey
+	metrics *metricdata.ResourceMetrics
+}
+
+// groupByBackend splits the gauges in rm by their backend_org and
+// backend_workspace values and removes both labels from the series: the
+// Pushgateway adds the grouping key to every series it serves, so they end
+// up with the labels they were recorded with.
+func groupByBackend(rm *metricdata.ResourceMetrics) []pushGroup {
+	groups := make(map[pushGroupKey]*metricdata.ScopeMetrics)
+	add := func(key pushGroupKey, m metricdata.Metrics) {
+		sm, ok := groups[key]
+		if !ok {
+			sm = &metricdata.ScopeMetrics{}
+			groups[key] = sm
+		}
+		sm.Metrics = append(sm.Metrics, m)
+	}
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				for key, points := range groupPoints(data.DataPoints) {
+					add(key, metricdata.Metrics{Name: m.Name, Description: m.Description, Unit: m.Unit, Data: metricdata.Gauge[int64]{DataPoints: points}})
+				}
+			case metricdata.Gauge[float64]:
+				for key, points := range groupPoints(data.DataPoints) {
+					add(key, metricdata.Metrics{Name: m.Name, Description: m.Description, Unit: m.Unit, Data: metricdata.Gauge[float64]{DataPoints: points}})
+				}
+			}
+		}
+	}
+
+	result := make([]pushGroup, 0, len(groups))
+	for key, sm := range groups {
+		result = append(result, pushGroup{key: key, metrics: &metricdata.ResourceMetrics{Resource: rm.Resource, ScopeMetrics: []metricdata.ScopeMetrics{*sm}}})
+	}
+	slices.SortFunc(result, func(a, b pushGroup) int {
+		return cmp.Or(strings.Compare(a.key.org, b.key.org), strings.Compare(a.key.workspace, b.key.workspace))
+	})
+	return result
+}
+
+func groupPoints[N int64 | float64](points []metricdata.DataPoint[N]) map[pushGroupKey][]metricdata.DataPoint[N] {
+	groups := make(map[pushGroupKey][]metricdata.DataPoint[N])
+	for _, dp := range points {
+		key := pushGroupKey{org: stringLabel(dp.Attributes, labelBackendOrg), workspace: stringLabel(dp.Attributes, labelBackendWorkspace)}
+		dp.Attributes, _ = dp.Attributes.Filter(func(kv attribute.KeyValue) bool {
+			return kv.Key != labelBackendOrg && kv.Key != labelBackendWorkspace
+		})
+		groups[key] = append(groups[key], dp)
+	}
+	return groups
+}
+
+// stringLabel returns the value of key in attrs, or "" if it is not set.
+func stringLabel(attrs attribute.Set, key attribute.Key) string {
+	if v, ok := attrs.Value(key); ok {
+		return v.Emit()
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package tfwatch
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// fakePushgateway records the pushes it receives, keyed by URL path.
+type fakePushgateway struct {
+	mu      sync.Mutex
+	methods []string
+	paths   []string
+	bodies  map[string]string
+	status  int
+}
+
+func (f *fakePushgateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.methods = append(f.methods, r.Method)
+	f.paths = append(f.paths, r.URL.EscapedPath())
+	if f.status != 0 {
+		http.Error(w, "rejected by test", f.status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	if f.bodies == nil {
+		f.bodies = make(map[string]string)
+	}
+	f.bodies[r.URL.EscapedPath()] = string(body)
+}
+
+func TestPushgatewayExporter(t *testing.T) {
+	point := func(org, ws, name string) metricdata.DataPoint[int64] {
+		return metricdata.DataPoint[int64]{Value: 1, Attributes: attribute.NewSet(
+			attribute.String("backend_type", "s3"),
+			attribute.String("backend_org", org),
+			attribute.String("backend_workspace", ws),
+			attribute.String("dependency_name", name),
+		)}
+	}
+	rm := &metricdata.ResourceMetrics{ScopeMetrics: []metricdata.ScopeMetrics{{Metrics: []metricdata.Metrics{{
+		Name: "terraform_dependency_version",
+		Data: metricdata.Gauge[int64]{DataPoints: []metricdata.DataPoint[int64]{
+			point("state-bucket", "prod/network.tfstate", "aws"),
+			point("state-bucket", "prod/network.tfstate", "vpc"),
+			point("", "dev", "random"),
+		}},
+	}}}}}
+
+	gateway := &fakePushgateway{}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+	exporter, err := NewPushgatewayExporter(PushgatewayConfig{URL: server.URL + "/", Job: "terraform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Export(context.Background(), rm); err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+	wantPaths := []string{
+		"/metrics/job/terraform/backend_org@base64/=/backend_workspace/dev",
+		"/metrics/job/terraform/backend_org/state-bucket/backend_workspace@base64/cHJvZC9uZXR3b3JrLnRmc3RhdGU",
+	}
+	if strings.Join(gateway.paths, "\n") != strings.Join(wantPaths, "\n") {
+		t.Fatalf("got paths:\n%s\nwant:\n%s", strings.Join(gateway.paths, "\n"), strings.Join(wantPaths, "\n"))
+	}
+	for _, m := range gateway.methods {
+		if m != http.MethodPut {
+			t.Errorf("expected PUT to replace the group, got %s", m)
+		}
+	}
+	// The grouping key labels are added back by the Pushgateway.
+	want := "# TYPE terraform_dependency_version gauge\n" +
+		"terraform_dependency_version{backend_type=\"s3\",dependency_name=\"aws\"} 1\n" +
+		"terraform_dependency_version{backend_type=\"s3\",dependency_name=\"vpc\"} 1\n"
+	if got := gateway.bodies[wantPaths[1]]; got != want {
+		t.Errorf("got body:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPushgatewayExporter_Errors(t *testing.T) {
+	if _, err := NewPushgatewayExporter(PushgatewayConfig{URL: "pushgateway:9091"}); err == nil {
+		t.Error("expected an error for a URL without scheme")
+	}
+
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int
+	}{
+		{name: "permanent", status: http.StatusBadRequest, wantCalls: 1},
+		{name: "retried", status: http.StatusServiceUnavailable, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := &fakePushgateway{status: tt.status}
+			server := httptest.NewServer(gateway)
+			defer server.Close()
+			exporter, err := NewPushgatewayExporter(PushgatewayConfig{URL: server.URL, RetryDeadline: 50 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			exporter.(*retryExporter).initial = 30 * time.Millisecond
+
+			if err := exporter.Export(context.Background(), testResourceMetrics(t)); err == nil {
+				t.Fatal("expected an error")
+			}
+			gateway.mu.Lock()
+			defer gateway.mu.Unlock()
+			if len(gateway.paths) != tt.wantCalls {
+				t.Errorf("expected %d requests, got %d", tt.wantCalls, len(gateway.paths))
+			}
+			if len(gateway.paths) > 0 && gateway.paths[0] != "/metrics/job/tfwatch/backend_org@base64/=/backend_workspace@base64/=" {
+				t.Errorf("unexpected path %s", gateway.paths[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package tfwatch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RegistryClient looks up available provider and module versions using the
+// Terraform registry protocols. It works against any host that implements
+// service discovery, including private registries. Responses are cached for
+// the lifetime of the client, so a dependency shared by many roots is only
+// fetched once.
+type RegistryClient struct {
+	HTTPClient *http.Client
+
+	// Token returns the bearer token for a registry host, or "" for none.
+	// Defaults to the TF_TOKEN_<host> environment variables used by Terraform.
+	Token func(host string) string
+
+	mu        sync.Mutex
+	discovery map[string]*cachedCall[map[string]string]
+	versions  map[string]*cachedCall[[]string]
+	checksums map[string]*cachedCall[string]
+}
+
+type cachedCall[T any] struct {
+	once  sync.Once
+	value T
+	err   error
+}
+
+// NewRegistryClient creates a RegistryClient with the given request timeout.
+func NewRegistryClient(timeout time.Duration) *RegistryClient {
+	return &RegistryClient{HTTPClient: &http.Client{Timeout: timeout}}
+}
+
+// Versions returns every version the registry lists for addr, in the order
+// the registry returns them.
+func (c *RegistryClient) Versions(ctx context.Context, addr RegistryAddress) ([]string, error) {
+	call := cached(c, &c.versions, addr.String())
+	call.once.Do(func() {
+		call.value, call.err = c.fetchVersions(ctx, addr)
+	})
+	return call.value, call.err
+}
+
+func (c *RegistryClient) fetchVersions(ctx context.Context, addr RegistryAddress) ([]string, error) {
+	service, path := "providers.v1", []string{addr.Namespace, addr.Name, "versions"}
+	if addr.IsModule() {
+		service, path = "modules.v1", []string{addr.Namespace, addr.Name, addr.System, "versions"}
+	}
+
+	base, err := c.serviceURL(ctx, addr.Hostname, service)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range path {
+		path[i] = url.PathEscape(p)
+	}
+	u, err := base.Parse(strings.Join(path, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s URL for %s: %w", service, addr.Hostname, err)
+	}
+
+	var versions []string
+	if addr.IsModule() {
+		var body struct {
+			Modules []struct {
+				Versions []struct {
+					Version string `json:"version"`
+				} `json:"versions"`
+			} `json:"modules"`
+		}
+		if err := c.getJSON(ctx, u, addr.Hostname, &body); err != nil {
+			return nil, err
+		}
+		for _, m := range body.Modules {
+			for _, v := range m.Versions {
+				versions = append(versions, v.Version)
+			}
+		}
+	} else {
+		var body struct {
+			Versions []struct {
+				Version string `json:"version"`
+			} `json:"versions"`
+		}
+		if err := c.getJSON(ctx, u, addr.Hostname, &body); err != nil {
+			return nil, err
+		}
+		for _, v := range body.Versions {
+			versions = append(versions, v.Version)
+		}
+	}
+	return versions, nil
+}
+
+// ErrNoPackage is returned by PackageChecksum if the registry has no package
+// of the provider version for the platform.
+var ErrNoPackage = errors.New("no package is published for this platform")
+
+// PackageChecksum returns the SHA-256 checksum, in hex, of the release
+// archive of a provider version for platform ("<os>_<arch>", e.g.
+// "linux_amd64"), from the registry's download endpoint. Terraform records
+// it in the lock file as a "zh:" hash. The archive itself is not fetched. A
+// 404 from the download endpoint is reported as ErrNoPackage.
+func (c *RegistryClient) PackageChecksum(ctx context.Context, addr RegistryAddress, version, platform string) (string, error) {
+	call := cached(c, &c.checksums, addr.String()+"/"+version+"/"+platform)
+	call.once.Do(func() {
+		call.value, call.err = c.fetchChecksum(ctx, addr, version, platform)
+	})
+	return call.value, call.err
+}
+
+func (c *RegistryClient) fetchChecksum(ctx context.Context, addr RegistryAddress, version, platform string) (string, error) {
+	goos, arch, ok := strings.Cut(platform, "_")
+	if !ok {
+		return "", fmt.Errorf("invalid platform %q", platform)
+	}
+	base, err := c.serviceURL(ctx, addr.Hostname, "providers.v1")
+	if err != nil {
+		return "", err
+	}
+	path := []string{addr.Namespace, addr.Name, version, "download", goos, arch}
+	for i, p := range path {
+		path[i] = url.PathEscape(p)
+	}
+	u, err := base.Parse(strings.Join(path, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid providers.v1 URL for %s: %w", addr.Hostname, err)
+	}
+
+	var body struct {
+		Shasum string `json:"shasum"`
+	}
+	var status *statusError
+	if err := c.getJSON(ctx, u, addr.Hostname, &body); errors.As(err, &status) && status.code == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %w", ErrNoPackage, err)
+	} else if err != nil {
+		return "", err
+	}
+	if body.Shasum == "" {
+		return "", fmt.Errorf("GET %s: no shasum in response", u.Redacted())
+	}
+	return strings.ToLower(body.Shasum), nil
+}
+
+// serviceURL resolves a service identifier (e.g. "providers.v1") for host
+// using /.well-known/terraform.json. The returned URL always ends in "/".
+func (c *RegistryClient) serviceURL(ctx context.Context, host, service string) (*url.URL, error) {
+	call := cached(c, &c.discovery, host)
+	call.once.Do(func() {
+		call.value, call.err = c.discover(ctx, host)
+	})
+	if call.err != nil {
+		return nil, call.err
+	}
+
+	raw, ok := call.value[service]
+	if !ok {
+		return nil, fmt.Errorf("registry %s does not support %s", host, service)
+	}
+	base, err := url.Parse("https://" + host + "/.well-known/terraform.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry host %q: %w", host, err)
+	}
+	u, err := base.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s URL for %s: %w", service, host, err)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+func (c *RegistryClient) discover(ctx context.Context, host string) (map[string]string, error) {
+	u, err := url.Parse("https://" + host + "/.well-known/terraform.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry host %q: %w", host, err)
+	}
+
+	// Values are usually strings, but some services (e.g. login.v1) are
+	// objects; only the string-valued ones are relevant here.
+	var body map[string]json.RawMessage
+	if err := c.getJSON(ctx, u, host, &body); err != nil {
+		return nil, fmt.Errorf("service discovery failed: %w", err)
+	}
+	services := make(map[string]string, len(body))
+	for name, raw := range body {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			services[name] = s
+		}
+	}
+	return services, nil
+}
+
+func (c *RegistryClient) getJSON(ctx context.Context, u *url.URL, host string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := c.token(host); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return &statusError{url: u.Redacted(), status: resp.Status, code: resp.StatusCode}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("GET %s: invalid response: %w", u.Redacted(), err)
+	}
+	return nil
+}
+
+// statusError is a registry response with a status other than 200 OK.
+type statusError struct {
+	url, status string
+	code        int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.url, e.status)
+}
+
+func (c *RegistryClient) token(host string) string {
+	if c.Token != nil {
+		return c.Token(host)
+	}
+	return os.Getenv(tokenEnvVar(host))
+}
+
+// tokenEnvVar returns the environment variable Terraform reads credentials
+// for host from: dots become "_" and dashes become "__", e.g.
+// "app.terraform.io" → "TF_TOKEN_app_terraform_io".
+func tokenEnvVar(host string) string {
+	host = strings.ReplaceAll(host, "-", "__")
+	host = strings.ReplaceAll(host, ".", "_")
+	return "TF_TOKEN_" + host
+}
+
+func cached[T any](c *RegistryClient, m *map[string]*cachedCall[T], key string) *cachedCall[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if *m == nil {
+		*m = make(map[string]*cachedCall[T])
+	}
+	call, ok := (*m)[key]
+	if !ok {
+		call = &cachedCall[T]{}
+		(*m)[key] = call
+	}
+	return call
+}
@@ -0,0 +1,202 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestRegistry starts a TLS registry stand-in serving the given provider
+// and module versions, keyed by "namespace/name[/system]".
+func newTestRegistry(t *testing.T, versions map[string][]string) (*httptest.Server, *RegistryClient) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/terraform.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"providers.v1":"/v1/providers/","modules.v1":"/api/registry/v1/modules","login.v1":{"client":"terraform-cli"}}`))
+	})
+	mux.HandleFunc("/v1/providers/", func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/providers/"), "/versions")
+		vs, ok := versions[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var items []string
+		for _, v := range vs {
+			items = append(items, `{"version":"`+v+`","protocols":["5.0"]}`)
+		}
+		w.Write([]byte(`{"versions":[` + strings.Join(items, ",") + `]}`))
+	})
+	mux.HandleFunc("/api/registry/v1/modules/", func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/registry/v1/modules/"), "/versions")
+		vs, ok := versions[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var items []string
+		for _, v := range vs {
+			items = append(items, `{"version":"`+v+`"}`)
+		}
+		w.Write([]byte(`{"modules":[{"source":"` + key + `","versions":[` + strings.Join(items, ",") + `]}]}`))
+	})
+
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &RegistryClient{HTTPClient: srv.Client(), Token: func(string) string { return "" }}
+}
+
+func TestRegistryClient_Versions(t *testing.T) {
+	srv, client := newTestRegistry(t, map[string][]string{
+		"hashicorp/aws":                 {"5.75.1", "5.76.0"},
+		"terraform-aws-modules/vpc/aws": {"5.1.2", "5.2.0"},
+	})
+	host := strings.TrimPrefix(srv.URL, "https://")
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		addr    RegistryAddress
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "provider",
+			addr: RegistryAddress{Hostname: host, Namespace: "hashicorp", Name: "aws"},
+			want: []string{"5.75.1", "5.76.0"},
+		},
+		{
+			name: "module with relative service path",
+			addr: RegistryAddress{Hostname: host, Namespace: "terraform-aws-modules", Name: "vpc", System: "aws"},
+			want: []string{"5.1.2", "5.2.0"},
+		},
+		{
+			name:    "not found",
+			addr:    RegistryAddress{Hostname: host, Namespace: "hashicorp", Name: "missing"},
+			wantErr: "404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := client.Versions(ctx, tt.addr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegistryClient_CachesAndAuthenticates(t *testing.T) {
+	var requests atomic.Int32
+	var auth atomic.Value
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		auth.Store(r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/.well-known/terraform.json":
+			w.Write([]byte(`{"providers.v1":"/v1/providers/"}`))
+		case "/v1/providers/acme/widget/versions":
+			w.Write([]byte(`{"versions":[{"version":"1.0.0"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	client := &RegistryClient{HTTPClient: srv.Client()}
+	t.Setenv(tokenEnvVar(host), "s3cret")
+
+	addr := RegistryAddress{Hostname: host, Namespace: "acme", Name: "widget"}
+	for range 3 {
+		if _, err := client.Versions(context.Background(), addr); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests (discovery + versions), got %d", n)
+	}
+	if got := auth.Load(); got != "Bearer s3cret" {
+		t.Errorf("expected bearer token from environment, got %q", got)
+	}
+
+	_, err := client.Versions(context.Background(), RegistryAddress{Hostname: host, Namespace: "acme", Name: "vpc", System: "aws"})
+	if err == nil || !strings.Contains(err.Error(), "does not support modules.v1") {
+		t.Errorf("expected unsupported service error, got %v", err)
+	}
+}
+
+func TestTokenEnvVar(t *testing.T) {
+	tests := map[string]string{
+		"app.terraform.io":    "TF_TOKEN_app_terraform_io",
+		"tf-registry.acme.io": "TF_TOKEN_tf__registry_acme_io",
+	}
+	for host, want := range tests {
+		if got := tokenEnvVar(host); got != want {
+			t.Errorf("tokenEnvVar(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestRegistryClient_PackageChecksum(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/.well-known/terraform.json":
+			w.Write([]byte(`{"providers.v1":"/v1/providers/"}`))
+		case "/v1/providers/hashicorp/aws/5.75.1/download/linux/amd64":
+			w.Write([]byte(`{"os":"linux","arch":"amd64","filename":"terraform-provider-aws_5.75.1_linux_amd64.zip","shasum":"ABC123"}`))
+		case "/v1/providers/hashicorp/aws/5.75.1/download/plan9/amd64":
+			w.Write([]byte(`{"os":"plan9","arch":"amd64"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "https://")
+	client := &RegistryClient{HTTPClient: srv.Client(), Token: func(string) string { return "" }}
+	addr := RegistryAddress{Hostname: host, Namespace: "hashicorp", Name: "aws"}
+	ctx := context.Background()
+
+	for range 2 {
+		sum, err := client.PackageChecksum(ctx, addr, "5.75.1", "linux_amd64")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sum != "abc123" {
+			t.Errorf("expected lower-case checksum abc123, got %q", sum)
+		}
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected 2 requests (discovery + download), got %d", n)
+	}
+
+	if _, err := client.PackageChecksum(ctx, addr, "5.75.1", "darwin_arm64"); !errors.Is(err, ErrNoPackage) || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected ErrNoPackage for a 404, got %v", err)
+	}
+	for platform, wantErr := range map[string]string{
+		"plan9_amd64": "no shasum",
+		"linux":       "invalid platform",
+	} {
+		if _, err := client.PackageChecksum(ctx, addr, "5.75.1", platform); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", platform, wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package tfwatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReportSchemaVersion identifies the layout of Report. It is bumped whenever
+// a field is removed or changes meaning; adding fields does not change it.
+// See docs/output.md for the documented schema.
+const ReportSchemaVersion = "1"
+
+// Report is the machine-readable result of a tfwatch run, written by
+// --output json and --output yaml.
+type Report struct {
+	SchemaVersion    string       `json:"schema_version" yaml:"schema_version"`
+	ToolVersion      string       `json:"tfwatch_version" yaml:"tfwatch_version"`
+	GeneratedAt      time.Time    `json:"generated_at" yaml:"generated_at"`
+	Phase            string       `json:"phase" yaml:"phase"`
+	TerraformVersion string       `json:"terraform_version" yaml:"terraform_version"`
+	Roots            []RootReport `json:"roots" yaml:"roots"`
+}
+
+// RootReport describes a single scanned root module directory.
+type RootReport struct {
+	Directory        string               `json:"directory" yaml:"directory"`
+	Resolution       string               `json:"resolution" yaml:"resolution"` // "init" or "static"
+	Backend          *BackendConfig       `json:"backend,omitempty" yaml:"backend,omitempty"`
+	BackendOrg       string               `json:"backend_org" yaml:"backend_org"`
+	BackendWorkspace string               `json:"backend_workspace" yaml:"backend_workspace"`
+	Modules          []Module             `json:"modules" yaml:"modules"`
+	Providers        []Provider           `json:"providers" yaml:"providers"`
+	Warnings         []string             `json:"warnings" yaml:"warnings"`
+	LockIssues       []LockIssue          `json:"lock_issues,omitempty" yaml:"lock_issues,omitempty"`
+	Outdated         []OutdatedDependency `json:"outdated,omitempty" yaml:"outdated,omitempty"`
+	PlatformCoverage []PlatformCoverage   `json:"platform_coverage,omitempty" yaml:"platform_coverage,omitempty"` // only with a platforms policy rule
+	Violations       []Violation          `json:"violations,omitempty" yaml:"violations,omitempty"`               // only with "tfwatch check"
+	Error            string               `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ReportOptions carries the run-level values recorded in a Report.
+type ReportOptions struct {
+	ToolVersion      string
+	Phase            string
+	TerraformVersion string // detected with "terraform version" if empty
+}
+
+func (o ReportOptions) withDefaults() ReportOptions {
+	if o.TerraformVersion == "" {
+		o.TerraformVersion = getTerraformVersion()
+	}
+	return o
+}
+
+// NewReport builds a Report from scan results, with each directory recorded
+// relative to root. Slices are never nil so that consumers always see arrays.
+func NewReport(root string, results []ScanResult, opts ReportOptions) *Report {
+	opts = opts.withDefaults()
+	report := &Report{
+		SchemaVersion:    ReportSchemaVersion,
+		ToolVersion:      opts.ToolVersion,
+		GeneratedAt:      time.Now().UTC().Truncate(time.Second),
+		Phase:            opts.Phase,
+		TerraformVersion: opts.TerraformVersion,
+		Roots:            make([]RootReport, 0, len(results)),
+	}
+
+	for _, res := range results {
+		rr := RootReport{
+			Directory:        relativeDir(root, res.Directory),
+			Resolution:       res.resolution(),
+			Backend:          res.Backend,
+			Modules:          nonNil(res.Modules),
+			Providers:        nonNil(res.Providers),
+			Warnings:         nonNil(res.Warnings),
+			LockIssues:       res.LockIssues,
+			Outdated:         res.Outdated,
+			PlatformCoverage: res.Platforms,
+			Violations:       res.Violations,
+		}
+		if res.Backend != nil {
+			rr.BackendOrg, rr.BackendWorkspace = res.Backend.Identity()
+		}
+		if res.Err != nil {
+			rr.Error = res.Err.Error()
+		}
+		report.Roots = append(report.Roots, rr)
+	}
+
+	return report
+}
+
+// Write encodes the report to w in the given format ("json" or "yaml").
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unsupported report format %q", format)
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+package tfwatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func sampleResults(root string) []ScanResult {
+	return []ScanResult{
+		{
+			Directory: filepath.Join(root, "prod", "network"),
+			Backend:   &BackendConfig{Type: "s3", Bucket: "state", Key: "network.tfstate"},
+			Modules:   []Module{{Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Version: "5.1.2"}},
+			Providers: []Provider{{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: "5.75.1"}},
+		},
+		{
+			Directory: filepath.Join(root, "prod", "broken"),
+			Err:       errors.New("failed to detect backend"),
+		},
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	root := t.TempDir()
+	report := NewReport(root, sampleResults(root), ReportOptions{
+		ToolVersion:      "1.2.3",
+		Phase:            "apply",
+		TerraformVersion: "1.9.8",
+	})
+
+	if report.SchemaVersion != ReportSchemaVersion {
+		t.Errorf("expected schema version %s, got %s", ReportSchemaVersion, report.SchemaVersion)
+	}
+	if report.TerraformVersion != "1.9.8" || report.Phase != "apply" || report.ToolVersion != "1.2.3" {
+		t.Errorf("unexpected run fields: %+v", report)
+	}
+	if len(report.Roots) != 2 {
+		t.Fatalf("expected 2 roots, got %d", len(report.Roots))
+	}
+
+	ok := report.Roots[0]
+	if ok.Directory != "prod/network" {
+		t.Errorf("expected directory prod/network, got %s", ok.Directory)
+	}
+	if ok.BackendOrg != "state" || ok.BackendWorkspace != "network.tfstate" {
+		t.Errorf("unexpected backend identity: %s / %s", ok.BackendOrg, ok.BackendWorkspace)
+	}
+	if ok.Warnings == nil {
+		t.Error("expected non-nil warnings slice")
+	}
+
+	broken := report.Roots[1]
+	if broken.Error != "failed to detect backend" {
+		t.Errorf("unexpected error: %q", broken.Error)
+	}
+	if broken.Backend != nil || broken.Modules == nil || broken.Providers == nil {
+		t.Errorf("expected nil backend and empty slices, got %+v", broken)
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	root := t.TempDir()
+	report := NewReport(root, sampleResults(root), ReportOptions{TerraformVersion: "1.9.8"})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, "json"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if decoded["schema_version"] != "1" {
+			t.Errorf("expected schema_version 1, got %v", decoded["schema_version"])
+		}
+		roots := decoded["roots"].([]any)
+		first := roots[0].(map[string]any)
+		if first["backend"].(map[string]any)["bucket"] != "state" {
+			t.Errorf("unexpected backend: %v", first["backend"])
+		}
+		if _, ok := first["error"]; ok {
+			t.Error("expected error to be omitted for successful root")
+		}
+		if mods := first["modules"].([]any); mods[0].(map[string]any)["version"] != "5.1.2" {
+			t.Errorf("unexpected modules: %v", mods)
+		}
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, "yaml"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var decoded Report
+		if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("invalid YAML: %v", err)
+		}
+		if len(decoded.Roots) != 2 || decoded.Roots[0].Providers[0].Name != "aws" {
+			t.Errorf("unexpected roundtrip: %+v", decoded.Roots)
+		}
+		if !strings.Contains(buf.String(), "schema_version: \"1\"") {
+			t.Errorf("expected schema_version key in YAML output:\n%s", buf.String())
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		if err := report.Write(&bytes.Buffer{}, "xml"); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Backoff between export attempts: it starts at retryInitialBackoff and
+// doubles up to retryMaxBackoff.
+const (
+	retryInitialBackoff = time.Second
+	retryMaxBackoff     = 10 * time.Second
+)
+
+// retryExporter retries failed exports with exponential backoff until
+// deadline has passed since the first attempt. The upstream exporters retry
+// on their own, bounded by the per-export timeout; this replaces that with
+// one policy for all protocols and a deadline that can exceed the timeout.
+type retryExporter struct {
+	sdkmetric.Exporter
+	deadline time.Duration
+
+	initial, max time.Duration // backoff bounds, shortened in tests
+}
+
+func newRetryExporter(exporter sdkmetric.Exporter, deadline time.Duration) *retryExporter {
+	return &retryExporter{Exporter: exporter, deadline: deadline, initial: retryInitialBackoff, max: retryMaxBackoff}
+}
+
+// Export sends rm, retrying until it is accepted, the error is permanent, or
+// the deadline would pass before the next attempt. The returned error is
+// that of the last attempt.
+func (e *retryExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	ctx, cancel := context.WithTimeout(ctx, e.deadline)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	backoff := e.initial
+	for attempt := 1; ; attempt++ {
+		err := e.Exporter.Export(ctx, rm)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+		if time.Until(deadline) < backoff {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, e.max)
+	}
+}
+
+// permanentError marks an export failure that retrying cannot fix, such as
+// a request the collector rejected as invalid or unauthorized.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// retryable reports whether an export that failed with err may succeed if
+// tried again. gRPC status codes are classified as in the OTLP spec; the
+// HTTP/JSON exporter marks rejected requests with permanentError. The
+// upstream HTTP/protobuf exporter does not expose the response status, so
+// its failures are always retried.
+func retryable(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted,
+			codes.OutOfRange, codes.Unavailable, codes.DataLoss:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
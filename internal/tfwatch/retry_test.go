@@ -0,0 +1,89 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyExporter fails with the given errors in turn, then succeeds.
+type flakyExporter struct {
+	sdkmetric.Exporter
+	errs  []error
+	calls int
+}
+
+func (f *flakyExporter) Export(context.Context, *metricdata.ResourceMetrics) error {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return f.errs[f.calls-1]
+	}
+	return nil
+}
+
+func TestRetryExporter(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	tests := []struct {
+		name      string
+		errs      []error
+		deadline  time.Duration
+		wantCalls int
+		wantErr   string
+	}{
+		{name: "success", deadline: time.Second, wantCalls: 1},
+		{name: "transient failures", errs: []error{unavailable, errors.New("connection reset")}, deadline: time.Second, wantCalls: 3},
+		{name: "permanent http error", errs: []error{&permanentError{errors.New("401 Unauthorized")}}, deadline: time.Second, wantCalls: 1, wantErr: "401 Unauthorized"},
+		{name: "permanent grpc error", errs: []error{status.Error(codes.InvalidArgument, "bad request")}, deadline: time.Second, wantCalls: 1, wantErr: "bad request"},
+		{
+			name:      "deadline",
+			errs:      []error{unavailable, unavailable, unavailable, unavailable, unavailable},
+			deadline:  100 * time.Millisecond,
+			wantCalls: 3, // after 0, 20 and 60ms; the next backoff of 80ms passes the deadline
+			wantErr:   "giving up after 3 attempts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyExporter{errs: tt.errs}
+			e := newRetryExporter(inner, tt.deadline)
+			e.initial, e.max = 20*time.Millisecond, 200*time.Millisecond
+
+			err := e.Export(context.Background(), &metricdata.ResourceMetrics{})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("expected %d attempts, got %d", tt.wantCalls, inner.calls)
+			}
+		})
+	}
+}
+
+func TestRetryExporter_Cancelled(t *testing.T) {
+	inner := &flakyExporter{errs: []error{errors.New("connection refused"), errors.New("connection refused")}}
+	e := newRetryExporter(inner, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if err := e.Export(ctx, &metricdata.ResourceMetrics{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second/2 {
+		t.Errorf("expected cancellation to stop the backoff, took %s", elapsed)
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected 1 attempt, got %d", inner.calls)
+	}
+}
@@ -0,0 +1,124 @@
+package tfwatch
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// sbomPackage is the format-neutral description of a module or provider
+// shared by the CycloneDX and SPDX writers.
+type sbomPackage struct {
+	Ref          string // stable identifier: purl when available, otherwise "<type>:<source>@<version>"
+	Type         string // "module" or "provider"
+	Name         string // module key or provider short name (dependency_name label)
+	Group        string // registry namespace
+	Package      string // registry package name, or Name for non-registry sources
+	Version      string
+	Source       string // dependency source with credentials removed
+	PURL         string
+	Location     string // registry page or VCS URL; empty for local sources
+	LocationType string // "distribution" or "vcs"
+	SHA256       []string
+	LockHashes   []string // "h1:" hashes, which have no standard SBOM algorithm
+}
+
+func moduleSBOMPackage(mod Module) sbomPackage {
+	pkg := sbomPackage{
+		Type:    "module",
+		Name:    mod.Name,
+		Package: mod.Name,
+		Version: mod.Version,
+		Source:  mod.Source,
+	}
+
+	if addr, ok := ParseModuleSource(mod.Source); ok {
+		pkg.Group = addr.Namespace
+		pkg.Package = addr.Name
+		pkg.PURL = addr.PURL(mod.Version)
+		pkg.Ref = pkg.PURL
+		pkg.Location = addr.RegistryURL(mod.Version)
+		pkg.LocationType = "distribution"
+		return pkg
+	}
+
+	// Remote sources may embed credentials; never copy them into an SBOM.
+	if loc := sanitizeSourceURL(mod.Source); loc != "" {
+		pkg.Source = loc
+		pkg.Location = loc
+		pkg.LocationType = "vcs"
+	}
+	pkg.Ref = "module:" + pkg.Source
+	if mod.Version != "" {
+		pkg.Ref += "@" + mod.Version
+	}
+	return pkg
+}
+
+func providerSBOMPackage(prov Provider) sbomPackage {
+	pkg := sbomPackage{
+		Ref:     "provider:" + prov.Source + "@" + prov.Version,
+		Type:    "provider",
+		Name:    prov.Name,
+		Package: prov.Name,
+		Version: prov.Version,
+		Source:  prov.Source,
+	}
+
+	if addr, ok := ParseProviderSource(prov.Source); ok {
+		pkg.Group = addr.Namespace
+		pkg.PURL = addr.PURL(prov.Version)
+		pkg.Ref = pkg.PURL
+		pkg.Location = addr.RegistryURL(prov.Version)
+		pkg.LocationType = "distribution"
+	}
+
+	// zh: hashes are SHA-256 sums of the per-platform release zips; h1: hashes
+	// cover the unpacked package contents.
+	for _, h := range prov.Hashes {
+		switch {
+		case strings.HasPrefix(h, "zh:"):
+			pkg.SHA256 = append(pkg.SHA256, strings.TrimPrefix(h, "zh:"))
+		case strings.HasPrefix(h, "h1:"):
+			pkg.LockHashes = append(pkg.LockHashes, h)
+		}
+	}
+	return pkg
+}
+
+// sbomPackages returns the packages of a scanned root in module-then-provider order.
+func sbomPackages(res ScanResult) []sbomPackage {
+	var pkgs []sbomPackage
+	for _, mod := range res.Modules {
+		pkgs = append(pkgs, moduleSBOMPackage(mod))
+	}
+	for _, prov := range res.Providers {
+		pkgs = append(pkgs, providerSBOMPackage(prov))
+	}
+	return pkgs
+}
+
+// scannedResults drops the roots that failed to scan.
+func scannedResults(results []ScanResult) []ScanResult {
+	var scanned []ScanResult
+	for _, res := range results {
+		if res.Err == nil {
+			scanned = append(scanned, res)
+		}
+	}
+	return scanned
+}
+
+// isSingleRoot reports whether results describe just the scan root itself,
+// in which case it is the subject of the SBOM rather than one of many roots.
+func isSingleRoot(root string, scanned []ScanResult) bool {
+	return len(scanned) == 1 && relativeDir(root, scanned[0].Directory) == "."
+}
+
+// dirName returns the base name of dir after resolving it to an absolute path,
+// so that "." yields the actual directory name.
+func dirName(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return filepath.Base(dir)
+}
@@ -0,0 +1,142 @@
+package tfwatch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ScanResult holds everything parsed from a single root module directory.
+type ScanResult struct {
+	Directory  string
+	Resolution string // ResolutionInit or ResolutionStatic
+	Backend    *BackendConfig
+	Modules    []Module
+	Providers  []Provider
+	Warnings   []string
+	LockIssues []LockIssue          // nil if there is no lock file
+	Outdated   []OutdatedDependency // nil unless CheckOutdated ran
+	Platforms  []PlatformCoverage   // nil unless CheckPlatforms ran
+	Violations []Violation          // nil unless CheckPolicy ran
+	Err        error
+}
+
+// ScanOptions controls how ScanRoots scans.
+type ScanOptions struct {
+	Concurrency     int // directories scanned in parallel (default 1)
+	InitConcurrency int // concurrent "terraform init" subprocesses (default 1)
+
+	// NoInit never runs terraform init, which needs backend credentials,
+	// and reads modules from their module blocks (ResolutionStatic).
+	// Providers are still read from a committed lock file.
+	NoInit bool
+}
+
+// ScanRoots parses every directory in dirs using a pool of workers and
+// returns one result per directory in the same order as dirs, regardless of
+// scheduling. "terraform init" runs are limited separately because parallel
+// inits contend for the shared plugin cache. Once ctx is cancelled no new
+// directories are started and the remaining results carry ctx.Err().
+func ScanRoots(ctx context.Context, dirs []string, opts ScanOptions) []ScanResult {
+	workers := max(opts.Concurrency, 1)
+	initSem := make(chan struct{}, max(opts.InitConcurrency, 1))
+
+	results := make([]ScanResult, len(dirs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(dirs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				parser := NewParser(dirs[i])
+				parser.initSem = initSem
+				parser.quietInit = true
+				parser.noInit = opts.NoInit
+				results[i] = scanDir(parser)
+			}
+		}()
+	}
+
+	for i, dir := range dirs {
+		if ctx.Err() != nil {
+			results[i] = ScanResult{Directory: dir, Err: ctx.Err()}
+			continue
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			results[i] = ScanResult{Directory: dir, Err: ctx.Err()}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// scanDir detects the backend, runs terraform init if needed, and parses
+// modules and providers for the parser's directory. Modules resolved by
+// terraform init also get the constraint and location of their module block,
+// and providers the constraints declared for them in required_providers. A
+// lock file is then checked against the providers the configuration requires.
+func scanDir(parser *Parser) (res ScanResult) {
+	res.Directory = parser.directory
+	res.Resolution = ResolutionInit
+	defer func() { res.Warnings = parser.Warnings() }()
+
+	backend, err := parser.ParseBackend()
+	if err != nil {
+		res.Err = fmt.Errorf("failed to detect backend: %w", err)
+		return res
+	}
+	res.Backend = backend
+
+	if parser.noInit {
+		res.Resolution = ResolutionStatic
+		res.Modules, err = parser.ParseModuleBlocks()
+		if err != nil {
+			res.Err = fmt.Errorf("failed to parse module blocks: %w", err)
+			return res
+		}
+	} else {
+		if err := parser.EnsureInit(); err != nil {
+			res.Err = fmt.Errorf("terraform init failed: %w", err)
+			return res
+		}
+		res.Modules, err = parser.ParseModules()
+		if err != nil {
+			res.Err = fmt.Errorf("failed to parse modules: %w", err)
+			return res
+		}
+		if declared, err := parser.ParseModuleBlocks(); err == nil {
+			addDeclarations(res.Modules, declared)
+		}
+	}
+
+	res.Providers, err = parser.ParseProviders()
+	if err != nil {
+		res.Err = fmt.Errorf("failed to parse providers: %w", err)
+		return res
+	}
+	if required, err := parser.ParseRequiredProviders(); err == nil {
+		addConstraints(res.Providers, required)
+	}
+	if !parser.needsInit() {
+		if required, complete, err := parser.providerRequirements(); err == nil {
+			res.LockIssues = checkLock(res.Providers, required, complete)
+		}
+	}
+
+	return res
+}
+
+// resolution returns how the modules were resolved; results built by hand
+// default to ResolutionInit.
+func (r ScanResult) resolution() string {
+	if r.Resolution == "" {
+		return ResolutionInit
+	}
+	return r.Resolution
+}
@@ -0,0 +1,131 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeTerraform puts a stub "terraform" on PATH whose init creates an empty
+// lock file and records how many inits were running at the same time.
+func fakeTerraform(t *testing.T) (stateDir string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake terraform stub requires a POSIX shell")
+	}
+
+	binDir := t.TempDir()
+	stateDir = t.TempDir()
+	script := `#!/bin/sh
+[ "$1" = "init" ] || exit 1
+touch "$TFWATCH_TEST_STATE/running.$$"
+ls "$TFWATCH_TEST_STATE" | grep -c '^running' > "$TFWATCH_TEST_STATE/seen.$$"
+sleep 0.1
+rm "$TFWATCH_TEST_STATE/running.$$"
+: > .terraform.lock.hcl
+`
+	if err := os.WriteFile(filepath.Join(binDir, "terraform"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TFWATCH_TEST_STATE", stateDir)
+	return stateDir
+}
+
+func makeRoots(t *testing.T, n int, withLock bool) []string {
+	t.Helper()
+	root := t.TempDir()
+	var dirs []string
+	for i := range n {
+		dir := filepath.Join(root, fmt.Sprintf("root-%02d", i))
+		os.MkdirAll(dir, 0o755)
+		os.WriteFile(filepath.Join(dir, "main.tf"), []byte(fmt.Sprintf(`
+terraform {
+  backend "s3" {
+    bucket = "bucket"
+    key    = "root-%02d.tfstate"
+  }
+}
+`, i)), 0o644)
+		if withLock {
+			os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(""), 0o644)
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+func TestScanRoots_DeterministicOrder(t *testing.T) {
+	dirs := makeRoots(t, 20, true)
+	// A broken root in the middle must not shift the others.
+	os.WriteFile(filepath.Join(dirs[7], "main.tf"), []byte(`terraform {}`), 0o644)
+
+	results := ScanRoots(context.Background(), dirs, ScanOptions{Concurrency: 8})
+
+	if len(results) != len(dirs) {
+		t.Fatalf("expected %d results, got %d", len(dirs), len(results))
+	}
+	for i, res := range results {
+		if res.Directory != dirs[i] {
+			t.Errorf("result %d: expected directory %s, got %s", i, dirs[i], res.Directory)
+		}
+		if i == 7 {
+			if res.Err == nil {
+				t.Error("expected error for broken root")
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Errorf("result %d: unexpected error: %v", i, res.Err)
+			continue
+		}
+		want := fmt.Sprintf("root-%02d.tfstate", i)
+		if res.Backend.Key != want {
+			t.Errorf("result %d: expected key %s, got %s", i, want, res.Backend.Key)
+		}
+	}
+}
+
+func TestScanRoots_InitConcurrency(t *testing.T) {
+	stateDir := fakeTerraform(t)
+	dirs := makeRoots(t, 6, false)
+
+	results := ScanRoots(context.Background(), dirs, ScanOptions{Concurrency: 6, InitConcurrency: 1})
+	for _, res := range results {
+		if res.Err != nil {
+			t.Fatalf("%s: unexpected error: %v", res.Directory, res.Err)
+		}
+	}
+
+	seen, _ := filepath.Glob(filepath.Join(stateDir, "seen.*"))
+	if len(seen) != len(dirs) {
+		t.Fatalf("expected %d init runs, got %d", len(dirs), len(seen))
+	}
+	for _, f := range seen {
+		data, _ := os.ReadFile(f)
+		if n := strings.TrimSpace(string(data)); n != "1" {
+			t.Errorf("expected 1 concurrent init, observed %s", n)
+		}
+	}
+}
+
+func TestScanRoots_Cancelled(t *testing.T) {
+	dirs := makeRoots(t, 3, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := ScanRoots(ctx, dirs, ScanOptions{Concurrency: 2})
+	for i, res := range results {
+		if res.Directory != dirs[i] {
+			t.Errorf("result %d: expected directory %s, got %s", i, dirs[i], res.Directory)
+		}
+		if !errors.Is(res.Err, context.Canceled) {
+			t.Errorf("result %d: expected context.Canceled, got %v", i, res.Err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package tfwatch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Server keeps the metrics of the most recent scan and serves them in the
+// Prometheus text format, for "tfwatch serve". Each Update records a complete
+// new snapshot and swaps it in atomically, so a scrape sees either the old or
+// the new state and series for versions that are no longer in use disappear
+// as soon as the scan completes.
+type Server struct {
+	config    CollectorConfig
+	root      string
+	recursive bool // label series with their directory, as PublishRoots does
+	resource  *resource.Resource
+
+	mu       sync.Mutex // serializes Update
+	lastGood map[string]ScanResult
+	current  atomic.Pointer[metricdata.ResourceMetrics]
+}
+
+// NewServer creates a Server that publishes scans of root with the given
+// collector settings. res is attached to every snapshot; it may be nil.
+func NewServer(cfg CollectorConfig, root string, recursive bool, res *resource.Resource) *Server {
+	cfg.Quiet = true
+	return &Server{
+		config:    cfg,
+		root:      root,
+		recursive: recursive,
+		resource:  res,
+		lastGood:  make(map[string]ScanResult),
+	}
+}
+
+// Update records results as the new current state and returns the snapshot
+// so that it can also be pushed elsewhere. A root that fails to scan keeps
+// the series of its last successful scan, so a transient "terraform init"
+// failure does not look like every dependency was removed; the failure is
+// still counted in tfwatch_scan_failed_roots.
+func (s *Server) Update(ctx context.Context, results []ScanResult) (*metricdata.ResourceMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failed := 0
+	published := make([]ScanResult, 0, len(results))
+	seen := make(map[string]bool, len(results))
+	for _, res := range results {
+		seen[res.Directory] = true
+		if res.Err == nil {
+			s.lastGood[res.Directory] = res
+			published = append(published, res)
+			continue
+		}
+		failed++
+		log.Printf("Error: %s: %v", relativeDir(s.root, res.Directory), res.Err)
+		if prev, ok := s.lastGood[res.Directory]; ok {
+			published = append(published, prev)
+		}
+	}
+
+	// Roots that are no longer discovered are gone, not failing.
+	for dir := range s.lastGood {
+		if !seen[dir] {
+			delete(s.lastGood, dir)
+		}
+	}
+
+	reader := sdkmetric.NewManualReader()
+	opts := []sdkmetric.Option{sdkmetric.WithReader(reader)}
+	if s.resource != nil {
+		opts = append(opts, sdkmetric.WithResource(s.resource))
+	}
+	provider := sdkmetric.NewMeterProvider(opts...)
+	defer func() { _ = provider.Shutdown(ctx) }()
+
+	cfg := s.config
+	cfg.MeterProvider = provider
+	collector := NewCollector(cfg)
+	for _, res := range published {
+		var extra []attribute.KeyValue
+		if s.recursive {
+			extra = directoryAttrs(s.root, res)
+		}
+		collector.publishResult(ctx, res, extra)
+	}
+
+	if err := recordScanStatus(ctx, provider.Meter("tfwatch"), len(results), failed); err != nil {
+		return nil, err
+	}
+
+	rm := &metricdata.ResourceMetrics{}
+	if err := reader.Collect(ctx, rm); err != nil {
+		return nil, fmt.Errorf("failed to collect metrics: %w", err)
+	}
+	s.current.Store(rm)
+	return rm, nil
+}
+
+func recordScanStatus(ctx context.Context, meter metric.Meter, roots, failed int) error {
+	timestamp, err := meter.Float64Gauge(
+		"tfwatch_last_scan_timestamp_seconds",
+		metric.WithDescription("Unix time at which the last scan completed"),
+	)
+	if err != nil {
+		return err
+	}
+	failedRoots, err := meter.Int64Gauge(
+		"tfwatch_scan_failed_roots",
+		metric.WithDescription("Root modules that failed in the last scan (their previous series are kept)"),
+	)
+	if err != nil {
+		return err
+	}
+	scannedRoots, err := meter.Int64Gauge(
+		"tfwatch_scan_roots",
+		metric.WithDescription("Root modules scanned in the last scan"),
+	)
+	if err != nil {
+		return err
+	}
+
+	timestamp.Record(ctx, float64(time.Now().UnixNano())/1e9)
+	failedRoots.Record(ctx, int64(failed))
+	scannedRoots.Record(ctx, int64(roots))
+	return nil
+}
+
+// ServeHTTP writes the current snapshot in the Prometheus text format. It
+// responds 503 until the first scan has completed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rm := s.current.Load()
+	if rm == nil {
+		http.Error(w, "first scan has not completed yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := WritePrometheus(w, rm); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+// Ready reports whether a scan has completed, for health checks.
+func (s *Server) Ready() bool {
+	return s.current.Load() != nil
+}
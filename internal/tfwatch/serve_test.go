@@ -0,0 +1,107 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(CollectorConfig{Phase: "post-apply"}, "/infra", true, nil)
+
+	scrape := func(t *testing.T) (int, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	if code, _ := scrape(t); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before the first scan, got %d", code)
+	}
+	if server.Ready() {
+		t.Fatal("expected server not to be ready before the first scan")
+	}
+
+	ctx := context.Background()
+	network := func(version string) ScanResult {
+		return ScanResult{
+			Directory: "/infra/network",
+			Backend:   &BackendConfig{Type: "s3", Bucket: "state", Key: "network.tfstate"},
+			Providers: []Provider{{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Version: version}},
+		}
+	}
+	dns := ScanResult{
+		Directory: "/infra/dns",
+		Backend:   &BackendConfig{Type: "s3", Bucket: "state", Key: "dns.tfstate"},
+		Providers: []Provider{{Name: "cloudflare", Source: "registry.terraform.io/cloudflare/cloudflare", Version: "4.0.0"}},
+	}
+
+	steps := []struct {
+		name     string
+		results  []ScanResult
+		want     []string
+		wantGone []string
+	}{
+		{
+			name:    "first scan",
+			results: []ScanResult{network("5.0.0"), dns},
+			want: []string{
+				`dependency_version="5.0.0"`,
+				`dependency_name="cloudflare"`,
+				`directory="network"`,
+				"tfwatch_scan_roots 2\n",
+				"tfwatch_scan_failed_roots 0\n",
+			},
+		},
+		{
+			name:     "upgrade replaces old series",
+			results:  []ScanResult{network("5.1.0"), dns},
+			want:     []string{`dependency_version="5.1.0"`},
+			wantGone: []string{`dependency_version="5.0.0"`},
+		},
+		{
+			name:    "failed root keeps last good series",
+			results: []ScanResult{{Directory: "/infra/network", Err: errors.New("init failed")}, dns},
+			want: []string{
+				`dependency_version="5.1.0"`,
+				"tfwatch_scan_failed_roots 1\n",
+			},
+		},
+		{
+			name:     "removed root disappears",
+			results:  []ScanResult{network("5.1.0")},
+			want:     []string{"tfwatch_scan_roots 1\n"},
+			wantGone: []string{`dependency_name="cloudflare"`},
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if _, err := server.Update(ctx, step.results); err != nil {
+				t.Fatalf("Update() error: %v", err)
+			}
+			code, body := scrape(t)
+			if code != http.StatusOK {
+				t.Fatalf("expected 200, got %d", code)
+			}
+			for _, s := range step.want {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected %q in:\n%s", s, body)
+				}
+			}
+			for _, s := range step.wantGone {
+				if strings.Contains(body, s) {
+					t.Errorf("unexpected %q in:\n%s", s, body)
+				}
+			}
+		})
+	}
+
+	if !server.Ready() {
+		t.Error("expected server to be ready")
+	}
+}
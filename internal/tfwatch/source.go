@@ -0,0 +1,176 @@
+package tfwatch
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DefaultRegistryHost is the registry hostname assumed when a source omits one.
+const DefaultRegistryHost = "registry.terraform.io"
+
+// RegistryAddress is a provider or module address on a Terraform registry.
+type RegistryAddress struct {
+	Hostname  string // e.g. "registry.terraform.io"
+	Namespace string // e.g. "hashicorp" or "terraform-aws-modules"
+	Name      string // provider type or module name, e.g. "aws" or "vpc"
+	System    string // module target system, e.g. "aws"; empty for providers
+}
+
+// IsModule reports whether the address refers to a module rather than a provider.
+func (a RegistryAddress) IsModule() bool {
+	return a.System != ""
+}
+
+// String returns the fully qualified address, e.g.
+// "registry.terraform.io/terraform-aws-modules/vpc/aws".
+func (a RegistryAddress) String() string {
+	parts := []string{a.Hostname, a.Namespace, a.Name}
+	if a.IsModule() {
+		parts = append(parts, a.System)
+	}
+	return strings.Join(parts, "/")
+}
+
+// PURL returns a package-URL-style identifier for the address at version,
+// e.g. "pkg:terraform/hashicorp/aws@5.75.1". Module addresses keep their
+// target system as an extra path segment. Non-default registries are
+// recorded in a repository_url qualifier.
+func (a RegistryAddress) PURL(version string) string {
+	segments := []string{a.Namespace, a.Name}
+	if a.IsModule() {
+		segments = append(segments, a.System)
+	}
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	purl := "pkg:terraform/" + strings.Join(segments, "/")
+	if version != "" {
+		purl += "@" + url.PathEscape(version)
+	}
+	if a.Hostname != DefaultRegistryHost {
+		purl += "?repository_url=" + url.QueryEscape(a.Hostname)
+	}
+	return purl
+}
+
+// RegistryURL returns the registry web page for the address at version,
+// e.g. "https://registry.terraform.io/providers/hashicorp/aws/5.75.1".
+func (a RegistryAddress) RegistryURL(version string) string {
+	kind := "providers"
+	path := []string{a.Namespace, a.Name}
+	if a.IsModule() {
+		kind = "modules"
+		path = append(path, a.System)
+	}
+	u := "https://" + a.Hostname + "/" + kind + "/" + strings.Join(path, "/")
+	if version != "" {
+		u += "/" + version
+	}
+	return u
+}
+
+// ParseProviderSource parses a provider source address of the form
+// "[hostname/]namespace/type", as found in .terraform.lock.hcl and
+// required_providers.
+func ParseProviderSource(source string) (RegistryAddress, bool) {
+	parts := strings.Split(source, "/")
+	switch len(parts) {
+	case 2:
+		parts = append([]string{DefaultRegistryHost}, parts...)
+	case 3:
+	default:
+		return RegistryAddress{}, false
+	}
+	if !validSourceParts(parts) {
+		return RegistryAddress{}, false
+	}
+
+	return RegistryAddress{
+		Hostname:  strings.ToLower(parts[0]),
+		Namespace: parts[1],
+		Name:      parts[2],
+	}, true
+}
+
+// ParseModuleSource parses a registry module source of the form
+// "[hostname/]namespace/name/system[//subdir]". Local paths, VCS and
+// archive sources are rejected.
+func ParseModuleSource(source string) (RegistryAddress, bool) {
+	if source == "" || strings.Contains(source, "::") || isLocalSource(source) || strings.HasPrefix(source, "/") {
+		return RegistryAddress{}, false
+	}
+	if i := strings.Index(source, "//"); i >= 0 {
+		source = source[:i]
+	}
+
+	parts := strings.Split(source, "/")
+	switch len(parts) {
+	case 3:
+		// Without a hostname the first segment must not look like one,
+		// otherwise it is a shorthand such as github.com/org/repo.
+		if looksLikeHost(parts[0]) {
+			return RegistryAddress{}, false
+		}
+		parts = append([]string{DefaultRegistryHost}, parts...)
+	case 4:
+		if !looksLikeHost(parts[0]) || isVCSHost(parts[0]) {
+			return RegistryAddress{}, false
+		}
+	default:
+		return RegistryAddress{}, false
+	}
+	if !validSourceParts(parts) {
+		return RegistryAddress{}, false
+	}
+
+	return RegistryAddress{
+		Hostname:  strings.ToLower(parts[0]),
+		Namespace: parts[1],
+		Name:      parts[2],
+		System:    parts[3],
+	}, true
+}
+
+func looksLikeHost(s string) bool {
+	return strings.ContainsAny(s, ".:")
+}
+
+func isVCSHost(host string) bool {
+	return host == "github.com" || host == "bitbucket.org"
+}
+
+func validSourceParts(parts []string) bool {
+	for _, p := range parts {
+		if p == "" || strings.ContainsAny(p, "?#@ ") {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeSourceURL returns a non-registry module source as a URL suitable
+// for publishing, with any go-getter forcing prefix ("git::") and embedded
+// credentials removed. It returns "" for local paths and unparsable sources.
+func sanitizeSourceURL(source string) string {
+	if source == "" || isLocalSource(source) {
+		return ""
+	}
+	if i := strings.Index(source, "::"); i >= 0 {
+		source = source[i+2:]
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	u.User = nil
+	q := u.Query()
+	for key := range q {
+		if key != "ref" {
+			q.Del(key)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,208 @@
+package tfwatch
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SPDXDocument is an SPDX 2.3 document describing the modules and providers
+// of one or more root modules, written by --output spdx.
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	DocumentDescribes []string           `json:"documentDescribes"`
+	Packages          []*spdxPackage     `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+	Comment  string   `json:"comment,omitempty"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	Checksums             []spdxChecksum    `json:"checksums,omitempty"`
+	LicenseConcluded      string            `json:"licenseConcluded"`
+	LicenseDeclared       string            `json:"licenseDeclared"`
+	CopyrightText         string            `json:"copyrightText"`
+	Comment               string            `json:"comment,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const spdxNoAssertion = "NOASSERTION"
+
+// NewSPDXDocument builds an SPDX document from scan results. Each root module
+// is an APPLICATION package described by the document and DEPENDS_ON its
+// modules and providers; dependencies shared by several roots are listed once.
+// Roots that failed to scan are skipped.
+func NewSPDXDocument(root string, results []ScanResult, opts ReportOptions) *SPDXDocument {
+	opts = opts.withDefaults()
+	name := "tfwatch-" + dirName(root)
+
+	doc := &SPDXDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + url.PathEscape(name) + "-" + newUUID(),
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: tfwatch-" + opts.ToolVersion},
+			Comment:  "terraform_version=" + opts.TerraformVersion + " phase=" + opts.Phase,
+		},
+		DocumentDescribes: []string{},
+		Packages:          []*spdxPackage{},
+		Relationships:     []spdxRelationship{},
+	}
+
+	ids := newSPDXIDs()
+	byRef := make(map[string]*spdxPackage)
+
+	for _, res := range scannedResults(results) {
+		rel := relativeDir(root, res.Directory)
+		org, ws := res.Backend.Identity()
+		rootPkg := &spdxPackage{
+			Name:                  dirName(res.Directory),
+			SPDXID:                ids.next(strings.TrimSuffix("root-"+rel, "-.")),
+			DownloadLocation:      spdxNoAssertion,
+			LicenseConcluded:      spdxNoAssertion,
+			LicenseDeclared:       spdxNoAssertion,
+			CopyrightText:         spdxNoAssertion,
+			Comment:               "Terraform root module " + rel + " (backend " + res.Backend.Type + " " + joinNonEmpty("/", org, ws) + ")",
+			PrimaryPackagePurpose: "APPLICATION",
+		}
+		doc.Packages = append(doc.Packages, rootPkg)
+		doc.DocumentDescribes = append(doc.DocumentDescribes, rootPkg.SPDXID)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{doc.SPDXID, "DESCRIBES", rootPkg.SPDXID})
+
+		for _, pkg := range sbomPackages(res) {
+			p, ok := byRef[pkg.Ref]
+			if !ok {
+				p = spdxPackageFor(pkg, ids.next(joinNonEmpty("-", pkg.Type, pkg.Group, pkg.Package, pkg.Version)))
+				byRef[pkg.Ref] = p
+				doc.Packages = append(doc.Packages, p)
+			} else {
+				p.Checksums = mergeChecksums(p.Checksums, pkg.SHA256)
+			}
+			rel := spdxRelationship{rootPkg.SPDXID, "DEPENDS_ON", p.SPDXID}
+			if !slices.Contains(doc.Relationships, rel) {
+				doc.Relationships = append(doc.Relationships, rel)
+			}
+		}
+	}
+
+	return doc
+}
+
+// Write encodes the document as indented JSON.
+func (d *SPDXDocument) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+func spdxPackageFor(pkg sbomPackage, id string) *spdxPackage {
+	p := &spdxPackage{
+		Name:                  pkg.Package,
+		SPDXID:                id,
+		VersionInfo:           pkg.Version,
+		DownloadLocation:      spdxNoAssertion,
+		LicenseConcluded:      spdxNoAssertion,
+		LicenseDeclared:       spdxNoAssertion,
+		CopyrightText:         spdxNoAssertion,
+		Comment:               "Terraform " + pkg.Type + " " + pkg.Name + " from " + pkg.Source,
+		PrimaryPackagePurpose: "LIBRARY",
+	}
+	if pkg.Group != "" {
+		p.Supplier = "Organization: " + pkg.Group
+	}
+
+	switch pkg.LocationType {
+	case "distribution":
+		p.DownloadLocation = pkg.Location
+	case "vcs":
+		// SPDX expresses VCS locations as <vcs>+<url>; go-getter sources
+		// without a "git::" prefix are plain downloads.
+		p.DownloadLocation = pkg.Location
+		if strings.HasPrefix(pkg.Source, "git::") || strings.HasSuffix(strings.SplitN(pkg.Location, "?", 2)[0], ".git") {
+			p.DownloadLocation = "git+" + pkg.Location
+		}
+	}
+
+	if pkg.PURL != "" {
+		p.ExternalRefs = []spdxExternalRef{{"PACKAGE-MANAGER", "purl", pkg.PURL}}
+	}
+	p.Checksums = mergeChecksums(nil, pkg.SHA256)
+	if len(pkg.LockHashes) > 0 {
+		p.Comment += "; lock file hashes: " + strings.Join(pkg.LockHashes, " ")
+	}
+	return p
+}
+
+func mergeChecksums(existing []spdxChecksum, sha256 []string) []spdxChecksum {
+	for _, h := range sha256 {
+		c := spdxChecksum{Algorithm: "SHA256", ChecksumValue: h}
+		if !slices.Contains(existing, c) {
+			existing = append(existing, c)
+		}
+	}
+	return existing
+}
+
+var spdxIDInvalid = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// spdxIDs hands out unique SPDX identifiers derived from readable names.
+type spdxIDs map[string]bool
+
+func newSPDXIDs() spdxIDs {
+	return make(spdxIDs)
+}
+
+// next returns "SPDXRef-<name>" with characters SPDX does not allow replaced
+// by "-", adding the lowest numeric suffix from 2 that makes it unique. A
+// suffixed identifier is taken too, so a later name that sanitizes to it
+// gets a suffix of its own.
+func (ids spdxIDs) next(name string) string {
+	base := "SPDXRef-" + strings.Trim(spdxIDInvalid.ReplaceAllString(name, "-"), "-")
+	id := base
+	for n := 2; ids[id]; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	ids[id] = true
+	return id
+}
@@ -0,0 +1,118 @@
+package tfwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// spoolExt is the extension of spooled payloads. Files being written carry a
+// leading dot and ".tmp" instead, so a crash never leaves a partial payload
+// that FlushSpool would pick up.
+const spoolExt = ".json"
+
+// SpoolMetrics writes rm to dir as an OTLP/JSON export request, for
+// FlushSpool to deliver later, and returns the file's path. The data points
+// keep the time they were recorded, so a late delivery shows up at the time
+// of the scan rather than of the replay.
+func SpoolMetrics(dir string, rm *metricdata.ResourceMetrics) (string, error) {
+	data, err := otlpJSON.Marshal(otlpRequest(rm))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode metrics: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".metrics-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to spool metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to spool metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to spool metrics: %w", err)
+	}
+
+	// Names sort in the order the payloads were written; the random part
+	// of the temporary name keeps concurrent runs from colliding.
+	suffix := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(tmp.Name()), ".metrics-"), ".tmp")
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102T150405.000000000Z")+"-"+suffix+spoolExt)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to spool metrics: %w", err)
+	}
+	return path, nil
+}
+
+// FlushSpool exports the payloads in dir, oldest first, and removes each
+// once it has been delivered. It stops at the first failed export and
+// returns the number delivered so far; the remaining payloads are kept for
+// the next attempt. A payload that cannot be parsed is renamed with an
+// ".invalid" suffix and skipped, so that it does not block the others; one
+// that cannot be read stops the flush and is kept. A missing dir has nothing
+// to flush.
+func FlushSpool(ctx context.Context, dir string, exporter sdkmetric.Exporter) (int, error) {
+	paths, err := spooledPayloads(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i, path := range paths {
+		payloads, err := readPayloadFile(path)
+		if err != nil && !errors.Is(err, errInvalidPayload) {
+			return delivered, fmt.Errorf("%d spooled payload(s) not delivered: %w", len(paths)-i, err)
+		}
+		if err != nil {
+			log.Printf("Warning: skipping %v", err)
+			if err := os.Rename(path, path+".invalid"); err != nil {
+				return delivered, fmt.Errorf("failed to set aside invalid payload: %w", err)
+			}
+			continue
+		}
+		for _, p := range payloads {
+			for _, rm := range p.metrics {
+				if err := exporter.Export(ctx, rm); err != nil {
+					return delivered, fmt.Errorf("%d spooled payload(s) not delivered: %w", len(paths)-i, err)
+				}
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return delivered, fmt.Errorf("failed to remove delivered payload: %w", err)
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// spooledPayloads returns the payload files in dir in the order they were
+// written.
+func spooledPayloads(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") && strings.HasSuffix(e.Name(), spoolExt) {
+			paths = append(paths, filepath.Join(dir, e.Name()))
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
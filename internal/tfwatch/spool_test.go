@@ -0,0 +1,155 @@
+package tfwatch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// spoolReceiver starts a fakeReceiver and returns an HTTP/JSON exporter
+// sending to it.
+func spoolReceiver(t *testing.T, status int) (*fakeReceiver, *jsonExporter) {
+	t.Helper()
+	receiver := &fakeReceiver{status: status}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	exporter, err := newJSONExporter(ExporterConfig{
+		Endpoint: strings.TrimPrefix(server.URL, "http://"),
+		Insecure: true,
+		URLPath:  DefaultURLPath,
+	}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return receiver, exporter
+}
+
+func TestFlushSpool(t *testing.T) {
+	dir := t.TempDir()
+	rm := testResourceMetrics(t)
+	recorded := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Gauge[int64]).DataPoints[0].Time
+	first, err := SpoolMetrics(dir, rm)
+	if err != nil {
+		t.Fatalf("SpoolMetrics() error: %v", err)
+	}
+	if _, err := SpoolMetrics(dir, testResourceMetrics(t)); err != nil {
+		t.Fatalf("SpoolMetrics() error: %v", err)
+	}
+	if filepath.Ext(first) != ".json" {
+		t.Errorf("unexpected payload name %s", first)
+	}
+
+	receiver, exporter := spoolReceiver(t, 0)
+	time.Sleep(10 * time.Millisecond) // replayed points must not be restamped
+	delivered, err := FlushSpool(context.Background(), dir, exporter)
+	if err != nil {
+		t.Fatalf("FlushSpool() error: %v", err)
+	}
+	if delivered != 2 {
+		t.Errorf("expected 2 payloads delivered, got %d", delivered)
+	}
+	if paths, _ := spooledPayloads(dir); len(paths) != 0 {
+		t.Errorf("expected an empty spool, got %v", paths)
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(receiver.requests))
+	}
+	point := receiver.requests[0].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetGauge().GetDataPoints()[0]
+	wantTime := unixNano(recorded)
+	if point.TimeUnixNano != wantTime {
+		t.Errorf("expected the original timestamp %d, got %d", wantTime, point.TimeUnixNano)
+	}
+}
+
+func TestFlushSpool_KeepsUndelivered(t *testing.T) {
+	dir := t.TempDir()
+	for range 2 {
+		if _, err := SpoolMetrics(dir, testResourceMetrics(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	receiver, exporter := spoolReceiver(t, http.StatusServiceUnavailable)
+	delivered, err := FlushSpool(context.Background(), dir, exporter)
+	if err == nil || !strings.Contains(err.Error(), "2 spooled payload(s) not delivered") {
+		t.Fatalf("expected an error for 2 payloads, got %v", err)
+	}
+	if delivered != 0 {
+		t.Errorf("expected nothing delivered, got %d", delivered)
+	}
+	if paths, _ := spooledPayloads(dir); len(paths) != 2 {
+		t.Errorf("expected both payloads kept, got %v", paths)
+	}
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.paths) != 1 {
+		t.Errorf("expected the flush to stop after the first failure, got %d requests", len(receiver.paths))
+	}
+}
+
+func TestFlushSpool_InvalidPayload(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "20260101T000000.000000000Z-1.json")
+	if err := os.WriteFile(invalid, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Partial writes of a concurrent run are not picked up.
+	if err := os.WriteFile(filepath.Join(dir, ".metrics-2.tmp"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SpoolMetrics(dir, testResourceMetrics(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, exporter := spoolReceiver(t, 0)
+	delivered, err := FlushSpool(context.Background(), dir, exporter)
+	if err != nil {
+		t.Fatalf("FlushSpool() error: %v", err)
+	}
+	if delivered != 1 {
+		t.Errorf("expected 1 payload delivered, got %d", delivered)
+	}
+	if _, err := os.Stat(invalid + ".invalid"); err != nil {
+		t.Errorf("expected the invalid payload to be set aside: %v", err)
+	}
+}
+
+func TestFlushSpool_UnreadablePayload(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	dir := t.TempDir()
+	path, err := SpoolMetrics(dir, testResourceMetrics(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	_, exporter := spoolReceiver(t, 0)
+	if _, err := FlushSpool(context.Background(), dir, exporter); err == nil {
+		t.Fatal("expected an error for an unreadable payload")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the unreadable payload to be kept: %v", err)
+	}
+}
+
+func TestFlushSpool_MissingDir(t *testing.T) {
+	_, exporter := spoolReceiver(t, 0)
+	delivered, err := FlushSpool(context.Background(), filepath.Join(t.TempDir(), "missing"), exporter)
+	if err != nil || delivered != 0 {
+		t.Errorf("expected nothing to do, got %d, %v", delivered, err)
+	}
+}
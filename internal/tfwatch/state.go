@@ -0,0 +1,178 @@
+package tfwatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// seriesStateVersion is bumped on incompatible changes to the state file.
+const seriesStateVersion = 1
+
+// SeriesState remembers which series were published for each root module, so
+// that the next run can retire the ones that are gone. OTLP push has no way
+// to delete a series: after an upgrade the old version's series would keep
+// its last value until the backend expires it. With a SeriesState the
+// Collector records 0 for such series instead.
+//
+// Roots are keyed by absolute directory and then by phase, since plan and
+// apply runs of the same root publish separate series.
+type SeriesState struct {
+	Version int                                     `json:"version"`
+	Roots   map[string]map[string][]PublishedSeries `json:"roots"`
+}
+
+// PublishedSeries is one recorded data point's metric name and labels.
+type PublishedSeries struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels"`
+}
+
+// NewSeriesState returns an empty state.
+func NewSeriesState() *SeriesState {
+	return &SeriesState{Version: seriesStateVersion, Roots: make(map[string]map[string][]PublishedSeries)}
+}
+
+// LoadSeriesState reads a state file written by Save. A missing file yields
+// an empty state, as on the first run.
+func LoadSeriesState(path string) (*SeriesState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewSeriesState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	state := NewSeriesState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.Version != seriesStateVersion {
+		return nil, fmt.Errorf("state file %s has unsupported version %d", path, state.Version)
+	}
+	if state.Roots == nil {
+		state.Roots = make(map[string]map[string][]PublishedSeries)
+	}
+	return state, nil
+}
+
+// Save writes the state to path atomically, so that an interrupted run
+// leaves the previous state intact.
+func (s *SeriesState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// replace stores the series published for dir in phase and returns the
+// previously stored series that are not among them.
+func (s *SeriesState) replace(dir, phase string, current []PublishedSeries) []PublishedSeries {
+	key := stateKey(dir)
+	seen := make(map[string]bool, len(current))
+	for _, ps := range current {
+		seen[ps.id()] = true
+	}
+
+	var stale []PublishedSeries
+	for _, ps := range s.Roots[key][phase] {
+		if !seen[ps.id()] {
+			stale = append(stale, ps)
+		}
+	}
+
+	if s.Roots[key] == nil {
+		s.Roots[key] = make(map[string][]PublishedSeries)
+	}
+	s.Roots[key][phase] = current
+	return stale
+}
+
+// removeMissing drops every root below parent that has series for phase but
+// is not in keep, and returns those series. It handles root modules deleted
+// from a repo, which are no longer discovered and so never published again.
+func (s *SeriesState) removeMissing(parent, phase string, keep map[string]bool) []PublishedSeries {
+	parent = stateKey(parent)
+	var stale []PublishedSeries
+	for _, key := range slices.Sorted(maps.Keys(s.Roots)) {
+		if keep[key] || !isWithin(parent, key) {
+			continue
+		}
+		if _, ok := s.Roots[key][phase]; !ok {
+			continue
+		}
+		stale = append(stale, s.Roots[key][phase]...)
+		delete(s.Roots[key], phase)
+		if len(s.Roots[key]) == 0 {
+			delete(s.Roots, key)
+		}
+	}
+	return stale
+}
+
+// isWithin reports whether dir is parent or below it.
+func isWithin(parent, dir string) bool {
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// stateKey returns the absolute form of dir, falling back to the cleaned
+// path if the working directory is unknown.
+func stateKey(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return filepath.Clean(dir)
+}
+
+func newPublishedSeries(name string, attrs []attribute.KeyValue) PublishedSeries {
+	labels := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		labels[string(kv.Key)] = kv.Value.Emit()
+	}
+	return PublishedSeries{Metric: name, Labels: labels}
+}
+
+// id returns a string identifying the series, for set comparisons.
+func (ps PublishedSeries) id() string {
+	var b strings.Builder
+	b.WriteString(ps.Metric)
+	for _, k := range slices.Sorted(maps.Keys(ps.Labels)) {
+		fmt.Fprintf(&b, "\x00%s=%s", k, ps.Labels[k])
+	}
+	return b.String()
+}
+
+// attrs returns the labels as OTEL attributes.
+func (ps PublishedSeries) attrs() []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(ps.Labels))
+	for _, k := range slices.Sorted(maps.Keys(ps.Labels)) {
+		attrs = append(attrs, attribute.String(k, ps.Labels[k]))
+	}
+	return attrs
+}
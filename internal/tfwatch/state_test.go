@@ -0,0 +1,130 @@
+package tfwatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestLoadSeriesState(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // empty means the file does not exist
+		wantErr string
+		roots   int
+	}{
+		{name: "missing file", roots: 0},
+		{
+			name:    "valid",
+			content: `{"version": 1, "roots": {"/infra/network": {"plan": [{"metric": "terraform_dependency_version", "labels": {"dependency_name": "aws"}}]}}}`,
+			roots:   1,
+		},
+		{name: "no roots", content: `{"version": 1}`, roots: 0},
+		{name: "unsupported version", content: `{"version": 2, "roots": {}}`, wantErr: "unsupported version 2"},
+		{name: "invalid JSON", content: `{`, wantErr: "failed to parse state file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			state, err := LoadSeriesState(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(state.Roots) != tt.roots {
+				t.Errorf("expected %d roots, got %d", tt.roots, len(state.Roots))
+			}
+		})
+	}
+}
+
+func TestSeriesState_SaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state := NewSeriesState()
+	state.replace("/infra/network", "apply", []PublishedSeries{
+		newPublishedSeries("terraform_dependency_version", []attribute.KeyValue{
+			attribute.String("dependency_name", "aws"),
+			attribute.String("dependency_version", "5.0.0"),
+		}),
+	})
+	if err := state.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := LoadSeriesState(path)
+	if err != nil {
+		t.Fatalf("LoadSeriesState() error: %v", err)
+	}
+	series := loaded.Roots["/infra/network"]["apply"]
+	if len(series) != 1 || series[0].Labels["dependency_version"] != "5.0.0" {
+		t.Errorf("unexpected series after round trip: %+v", series)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the state file, got %d entries", len(entries))
+	}
+}
+
+func TestSeriesState_Replace(t *testing.T) {
+	series := func(version string) PublishedSeries {
+		return PublishedSeries{Metric: "terraform_dependency_version", Labels: map[string]string{"dependency_name": "aws", "dependency_version": version}}
+	}
+	state := NewSeriesState()
+
+	if stale := state.replace("/infra/network", "plan", []PublishedSeries{series("5.0.0")}); len(stale) != 0 {
+		t.Fatalf("expected nothing stale on the first run, got %v", stale)
+	}
+	if stale := state.replace("/infra/network", "plan", []PublishedSeries{series("5.0.0")}); len(stale) != 0 {
+		t.Fatalf("expected nothing stale when unchanged, got %v", stale)
+	}
+	// Another phase of the same root is tracked separately.
+	if stale := state.replace("/infra/network", "apply", []PublishedSeries{series("4.0.0")}); len(stale) != 0 {
+		t.Fatalf("expected phases to be separate, got %v", stale)
+	}
+
+	stale := state.replace("/infra/network", "plan", []PublishedSeries{series("5.1.0")})
+	if len(stale) != 1 || stale[0].Labels["dependency_version"] != "5.0.0" {
+		t.Fatalf("expected 5.0.0 to be stale, got %v", stale)
+	}
+}
+
+func TestSeriesState_RemoveMissing(t *testing.T) {
+	series := []PublishedSeries{{Metric: "terraform_dependency_version", Labels: map[string]string{"dependency_name": "aws"}}}
+	state := NewSeriesState()
+	for _, dir := range []string{"/infra/network", "/infra/dns", "/infra-other/app", "/other/app"} {
+		state.replace(dir, "plan", series)
+	}
+	state.replace("/infra/dns", "apply", series)
+
+	stale := state.removeMissing("/infra", "plan", map[string]bool{"/infra/network": true})
+	if len(stale) != 1 {
+		t.Fatalf("expected the series of /infra/dns to be stale, got %v", stale)
+	}
+	if _, ok := state.Roots["/infra/dns"]["plan"]; ok {
+		t.Error("expected /infra/dns plan series to be removed")
+	}
+	if _, ok := state.Roots["/infra/dns"]["apply"]; !ok {
+		t.Error("expected /infra/dns apply series to be kept")
+	}
+	for _, dir := range []string{"/infra/network", "/infra-other/app", "/other/app"} {
+		if _, ok := state.Roots[dir]; !ok {
+			t.Errorf("expected %s to be kept", dir)
+		}
+	}
+}
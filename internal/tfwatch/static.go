@@ -0,0 +1,295 @@
+package tfwatch
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// How the modules of a ScanResult were resolved.
+const (
+	// ResolutionInit reads the modules terraform init installed, with their
+	// resolved versions. It is the default.
+	ResolutionInit = "init"
+	// ResolutionStatic reads the module blocks of the configuration without
+	// running terraform init; registry modules have a version constraint but
+	// no resolved version.
+	ResolutionStatic = "static"
+)
+
+// ParseModuleBlocks reads the module blocks in the directory's *.tf files,
+// without terraform init, and returns one Module per block with its source,
+// version constraint and location. Local module sources ("./" and "../")
+// are followed recursively; their modules are named like the keys of
+// modules.json, e.g. "network.subnets". Files that cannot be parsed and
+// local sources that do not exist are reported as warnings.
+func (p *Parser) ParseModuleBlocks() ([]Module, error) {
+	cfg, err := p.staticConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.modules, nil
+}
+
+// configTree is the part of a configuration that can be read without
+// terraform init: the module blocks of the root and of every local module
+// it reaches, and the directories of those modules.
+type configTree struct {
+	modules []Module
+	dirs    []moduleDir
+}
+
+// moduleDir is the directory of the root module (key "") or of a local
+// module, keyed like Module.Name, with its parsed *.tf files.
+type moduleDir struct {
+	key, dir string
+	files    []*hcl.File
+}
+
+// staticConfig walks the configuration once and caches the result, so that
+// its warnings are only reported once.
+func (p *Parser) staticConfig() (*configTree, error) {
+	if p.tree != nil {
+		return p.tree, nil
+	}
+	if _, err := os.Stat(p.directory); err != nil {
+		return nil, err
+	}
+	p.tree = &configTree{}
+	p.walkModules(p.directory, "", map[string]bool{})
+	return p.tree, nil
+}
+
+// walkModules collects the module blocks of dir, prefixing their names with
+// prefix. visiting holds the directories on the current path, so that a
+// cycle of local sources ends instead of recursing forever.
+func (p *Parser) walkModules(dir, prefix string, visiting map[string]bool) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = filepath.Clean(dir)
+	}
+	visiting[abs] = true
+	defer delete(visiting, abs)
+	md := moduleDir{key: strings.TrimSuffix(prefix, "."), dir: dir, files: p.parseDir(dir)}
+	p.tree.dirs = append(p.tree.dirs, md)
+
+	for _, block := range configBlocks(md.files, hcl.BlockHeaderSchema{Type: "module", LabelNames: []string{"name"}}) {
+		attrs, _ := block.Body.JustAttributes()
+		source := stringAttr(attrs, "source")
+		mod := Module{
+			Name:       prefix + block.Labels[0],
+			Source:     source,
+			Constraint: stringAttr(attrs, "version"),
+			Location:   p.location(block.DefRange),
+		}
+		p.tree.modules = append(p.tree.modules, mod)
+
+		if !isLocalSource(source) {
+			continue
+		}
+		child := filepath.Join(dir, source)
+		childAbs, _ := filepath.Abs(child)
+		switch info, err := os.Stat(child); {
+		case err != nil || !info.IsDir():
+			p.warnf("module %s: local source %s not found", mod.Name, source)
+		case visiting[childAbs]:
+			p.warnf("module %s: local source %s includes itself", mod.Name, source)
+		default:
+			p.walkModules(child, mod.Name+".", visiting)
+		}
+	}
+}
+
+// parseDir parses the *.tf files of dir, in name order. Files that cannot be
+// read or parsed are skipped with a warning.
+func (p *Parser) parseDir(dir string) []*hcl.File {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+	parser := hclparse.NewParser()
+
+	var files []*hcl.File
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			p.warnf("%v", err)
+			continue
+		}
+		f, diag := parser.ParseHCL(data, path)
+		if diag.HasErrors() {
+			p.warnf("skipping %s: %s", p.relative(path), diag.Error())
+			continue
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// configBlocks returns the top-level blocks matching schema in files, in
+// file order.
+func configBlocks(files []*hcl.File, schema hcl.BlockHeaderSchema) []*hcl.Block {
+	var blocks []*hcl.Block
+	for _, f := range files {
+		content, _, _ := f.Body.PartialContent(&hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{schema}})
+		if content != nil {
+			blocks = append(blocks, content.Blocks...)
+		}
+	}
+	return blocks
+}
+
+// location formats the start of r as "file:line", relative to the parser's
+// directory.
+func (p *Parser) location(r hcl.Range) string {
+	return fmt.Sprintf("%s:%d", p.relative(r.Filename), r.Start.Line)
+}
+
+func (p *Parser) relative(path string) string {
+	return relativeDir(p.directory, path)
+}
+
+// addDeclarations copies the version constraint and location of each
+// declared module onto the resolved module with the same name.
+func addDeclarations(resolved, declared []Module) {
+	byName := make(map[string]Module, len(declared))
+	for _, m := range declared {
+		byName[m.Name] = m
+	}
+	for i := range resolved {
+		if d, ok := byName[resolved[i].Name]; ok {
+			resolved[i].Constraint = d.Constraint
+			resolved[i].Location = d.Location
+		}
+	}
+}
+
+// RequiredProvider is an entry of a required_providers block in the root
+// module or a local module.
+type RequiredProvider struct {
+	Name       string // local name, e.g. "aws"
+	Source     string // fully qualified, e.g. "registry.terraform.io/hashicorp/aws"
+	Module     string // key of the declaring module; "" for the root module
+	Constraint string // version argument; "" if the entry has none
+	Location   string // entry as "file:line", relative to the root
+}
+
+// ParseRequiredProviders reads the required_providers blocks of the root
+// module and of every local module it reaches, without terraform init.
+// Sources are qualified like those in .terraform.lock.hcl; an entry without
+// a source implies "hashicorp/<name>", as in Terraform. The legacy form
+// aws = "~> 5.0" is read as a version constraint.
+func (p *Parser) ParseRequiredProviders() ([]RequiredProvider, error) {
+	cfg, err := p.staticConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var required []RequiredProvider
+	for _, md := range cfg.dirs {
+		required = append(required, p.requiredProviders(md)...)
+	}
+	return required, nil
+}
+
+// requiredProviders returns the required_providers entries of one module.
+func (p *Parser) requiredProviders(md moduleDir) []RequiredProvider {
+	var required []RequiredProvider
+	for _, tfBlock := range configBlocks(md.files, hcl.BlockHeaderSchema{Type: "terraform"}) {
+		content, _, _ := tfBlock.Body.PartialContent(&hcl.BodySchema{
+			Blocks: []hcl.BlockHeaderSchema{{Type: "required_providers"}},
+		})
+		if content == nil {
+			continue
+		}
+		for _, block := range content.Blocks {
+			attrs, _ := block.Body.JustAttributes()
+			for _, attr := range sortedAttributes(attrs) {
+				source, constraint := requiredProviderEntry(attr.Expr)
+				if source == "" {
+					source = "hashicorp/" + attr.Name
+				}
+				required = append(required, RequiredProvider{
+					Name:       attr.Name,
+					Source:     qualifiedProviderSource(source),
+					Module:     md.key,
+					Constraint: constraint,
+					Location:   p.location(attr.NameRange),
+				})
+			}
+		}
+	}
+	return required
+}
+
+// requiredProviderEntry returns the source and version of a required_providers
+// entry. Only these two keys are evaluated, because configuration_aliases
+// holds references that cannot be evaluated without context.
+func requiredProviderEntry(expr hcl.Expression) (source, constraint string) {
+	pairs, diag := hcl.ExprMap(expr)
+	if diag.HasErrors() {
+		return "", exprString(expr)
+	}
+	for _, pair := range pairs {
+		switch exprString(pair.Key) {
+		case "source":
+			source = exprString(pair.Value)
+		case "version":
+			constraint = exprString(pair.Value)
+		}
+	}
+	return source, constraint
+}
+
+// exprString returns the value of a literal string expression, or "".
+func exprString(expr hcl.Expression) string {
+	val, diag := expr.Value(nil)
+	if diag.HasErrors() || val.IsNull() || !val.IsWhollyKnown() || val.Type() != cty.String {
+		return ""
+	}
+	return val.AsString()
+}
+
+// qualifiedProviderSource returns source with the default registry host
+// added and in lower case, as Terraform writes it to the lock file. Sources
+// that cannot be parsed are returned unchanged.
+func qualifiedProviderSource(source string) string {
+	addr, ok := ParseProviderSource(source)
+	if !ok {
+		return source
+	}
+	return strings.ToLower(addr.String())
+}
+
+// sortedAttributes returns attrs in source order; hcl.Attributes is a map.
+func sortedAttributes(attrs hcl.Attributes) []*hcl.Attribute {
+	sorted := make([]*hcl.Attribute, 0, len(attrs))
+	for _, attr := range attrs {
+		sorted = append(sorted, attr)
+	}
+	slices.SortFunc(sorted, func(a, b *hcl.Attribute) int {
+		return cmp.Compare(a.Range.Start.Byte, b.Range.Start.Byte)
+	})
+	return sorted
+}
+
+// addConstraints records on each provider the constraints declared for its
+// source in required_providers.
+func addConstraints(providers []Provider, required []RequiredProvider) {
+	for i := range providers {
+		source := qualifiedProviderSource(providers[i].Source)
+		for _, r := range required {
+			if r.Source == source {
+				providers[i].Constraints = append(providers[i].Constraints, ProviderConstraint{
+					Module:     r.Module,
+					Constraint: r.Constraint,
+					Location:   r.Location,
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package tfwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files below dir from a map of relative paths to content.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// staticConfig is a root with a registry module, a local module that nests
+// another, and a Git module.
+var staticConfig = map[string]string{
+	"main.tf": `terraform {
+  backend "s3" {
+    bucket = "state"
+    key    = "network.tfstate"
+  }
+  required_providers {
+    aws = {
+      source  = "hashicorp/aws"
+      version = "~> 5.0"
+    }
+    random = {
+      source = "hashicorp/random"
+    }
+  }
+}
+
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "~> 5.1"
+}
+`,
+	"network.tf": `module "network" {
+  source = "./modules/network"
+}
+
+module "tags" {
+  source = "git::https://github.com/acme/terraform-tags.git?ref=v1.2.0"
+}
+`,
+	"modules/network/main.tf": `module "subnets" {
+  source  = "hashicorp/subnets/cidr"
+  version = ">= 1.0.0, < 2.0.0"
+}
+`,
+	"modules/network/versions.tf": `terraform {
+  required_providers {
+    aws = {
+      source                = "HashiCorp/AWS"
+      version               = ">= 5.10.0"
+      configuration_aliases = [aws.peer]
+    }
+    null = ">= 3.0"
+  }
+}
+`,
+}
+
+func TestParseModuleBlocks(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		want         []Module
+		wantWarnings []string
+	}{
+		{
+			name:  "registry, local and git modules",
+			files: staticConfig,
+			want: []Module{
+				{Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Constraint: "~> 5.1", Location: "main.tf:17"},
+				{Name: "network", Source: "./modules/network", Location: "network.tf:1"},
+				{Name: "network.subnets", Source: "hashicorp/subnets/cidr", Constraint: ">= 1.0.0, < 2.0.0", Location: "modules/network/main.tf:1"},
+				{Name: "tags", Source: "git::https://github.com/acme/terraform-tags.git?ref=v1.2.0", Location: "network.tf:5"},
+			},
+		},
+		{
+			name: "missing local source",
+			files: map[string]string{
+				"main.tf": "module \"gone\" {\n  source = \"./modules/gone\"\n}\n",
+			},
+			want:         []Module{{Name: "gone", Source: "./modules/gone", Location: "main.tf:1"}},
+			wantWarnings: []string{"module gone: local source ./modules/gone not found"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.tf":   "module \"a\" {\n  source = \"./a\"\n}\n",
+				"a/main.tf": "module \"back\" {\n  source = \"../\"\n}\n",
+			},
+			want: []Module{
+				{Name: "a", Source: "./a", Location: "main.tf:1"},
+				{Name: "a.back", Source: "../", Location: "a/main.tf:1"},
+			},
+			wantWarnings: []string{"module a.back: local source ../ includes itself"},
+		},
+		{
+			name: "unparsable file",
+			files: map[string]string{
+				"main.tf":   "module \"vpc\" {\n  source = \"terraform-aws-modules/vpc/aws\"\n}\n",
+				"broken.tf": "module \"x\" {\n",
+			},
+			want:         []Module{{Name: "vpc", Source: "terraform-aws-modules/vpc/aws", Location: "main.tf:1"}},
+			wantWarnings: []string{"skipping broken.tf"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			parser := NewParser(dir)
+			got, err := parser.ParseModuleBlocks()
+			if err != nil {
+				t.Fatalf("ParseModuleBlocks() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d modules, got %+v", len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("module %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+			warnings := parser.Warnings()
+			if len(warnings) != len(tt.wantWarnings) {
+				t.Fatalf("expected warnings %q, got %q", tt.wantWarnings, warnings)
+			}
+			for i, w := range tt.wantWarnings {
+				if !strings.Contains(warnings[i], w) {
+					t.Errorf("expected warning containing %q, got %q", w, warnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScanRoots_NoInit(t *testing.T) {
+	fakeTerraform(t) // its init would create a lock file
+	dir := t.TempDir()
+	writeFiles(t, dir, staticConfig)
+
+	results := ScanRoots(context.Background(), []string{dir}, ScanOptions{NoInit: true})
+	res := results[0]
+	if res.Err != nil {
+		t.Fatalf("unexpected error: %v", res.Err)
+	}
+	if res.Resolution != ResolutionStatic {
+		t.Errorf("expected resolution static, got %q", res.Resolution)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".terraform.lock.hcl")); err == nil {
+		t.Error("expected terraform init not to run")
+	}
+	if len(res.Modules) != 4 || res.Modules[0].Constraint != "~> 5.1" || res.Modules[0].Version != "" {
+		t.Errorf("unexpected modules: %+v", res.Modules)
+	}
+}
+
+func TestScanDir_WarnsOncePerFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.tf":   "terraform {\n  backend \"local\" {}\n}\n",
+		"broken.tf": "terraform {\n",
+	})
+	parser := NewParser(dir)
+	parser.noInit = true
+
+	res := scanDir(parser)
+	skipped := 0
+	for _, w := range res.Warnings {
+		if strings.HasPrefix(w, "skipping broken.tf") {
+			skipped++
+		}
+	}
+	if skipped != 1 {
+		t.Errorf("expected broken.tf to be reported once, got %q", res.Warnings)
+	}
+}
+
+func TestScanDir_AddsDeclarations(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, staticConfig)
+	writeFiles(t, dir, map[string]string{
+		".terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.75.1"
+  constraints = ">= 5.10.0, ~> 5.0"
+}
+`,
+		".terraform/modules/modules.json": `{"Modules":[
+			{"Key":"","Source":"","Dir":"."},
+			{"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Version":"5.1.2","Dir":".terraform/modules/vpc"},
+			{"Key":"network.subnets","Source":"registry.terraform.io/hashicorp/subnets/cidr","Version":"1.0.0","Dir":".terraform/modules/network.subnets"}
+		]}`,
+	})
+
+	res := scanDir(NewParser(dir))
+	if res.Err != nil {
+		t.Fatalf("unexpected error: %v", res.Err)
+	}
+	if res.Resolution != ResolutionInit {
+		t.Errorf("expected resolution init, got %q", res.Resolution)
+	}
+	want := []Module{
+		{Name: "vpc", Source: "registry.terraform.io/terraform-aws-modules/vpc/aws", Version: "5.1.2", Constraint: "~> 5.1", Location: "main.tf:17"},
+		{Name: "network.subnets", Source: "registry.terraform.io/hashicorp/subnets/cidr", Version: "1.0.0", Constraint: ">= 1.0.0, < 2.0.0", Location: "modules/network/main.tf:1"},
+	}
+	if len(res.Modules) != len(want) {
+		t.Fatalf("expected %d modules, got %+v", len(want), res.Modules)
+	}
+	for i := range want {
+		if res.Modules[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], res.Modules[i])
+		}
+	}
+	// Providers get the constraints every module declares for them.
+	if len(res.Providers) != 1 {
+		t.Fatalf("expected 1 provider, got %+v", res.Providers)
+	}
+	aws := res.Providers[0]
+	if len(aws.Constraints) != 2 || aws.Constraints[1].Module != "network" || aws.Constraint() != ">= 5.10.0, ~> 5.0" {
+		t.Errorf("unexpected constraints: %+v", aws)
+	}
+}
+
+func TestParseRequiredProviders(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, staticConfig)
+
+	got, err := NewParser(dir).ParseRequiredProviders()
+	if err != nil {
+		t.Fatalf("ParseRequiredProviders() error: %v", err)
+	}
+	want := []RequiredProvider{
+		{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Constraint: "~> 5.0", Location: "main.tf:7"},
+		{Name: "random", Source: "registry.terraform.io/hashicorp/random", Location: "main.tf:11"},
+		{Name: "aws", Source: "registry.terraform.io/hashicorp/aws", Module: "network", Constraint: ">= 5.10.0", Location: "modules/network/versions.tf:3"},
+		{Name: "null", Source: "registry.terraform.io/hashicorp/null", Module: "network", Constraint: ">= 3.0", Location: "modules/network/versions.tf:8"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
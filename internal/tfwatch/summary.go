@@ -0,0 +1,24 @@
+package tfwatch
+
+import "fmt"
+
+// RootError records a root module directory that could not be scanned.
+type RootError struct {
+	Directory string
+	Err       error
+}
+
+// ScanSummary reports which root modules were scanned and which failed
+// during a multi-root run.
+type ScanSummary struct {
+	Scanned []string
+	Failed  []RootError
+}
+
+// Print writes a per-root summary of the run to stdout.
+func (s *ScanSummary) Print() {
+	fmt.Printf("\nScanned %d root(s), %d failed\n", len(s.Scanned)+len(s.Failed), len(s.Failed))
+	for _, f := range s.Failed {
+		fmt.Printf("  FAILED %-30s %v\n", f.Directory, f.Err)
+	}
+}
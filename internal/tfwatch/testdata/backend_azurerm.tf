@@ -0,0 +1,8 @@
+terraform {
+  backend "azurerm" {
+    resource_group_name  = "tfstate"
+    storage_account_name = "acmetfstate"
+    container_name       = "tfstate"
+    key                  = "prod/network.tfstate"
+  }
+}
@@ -0,0 +1,7 @@
+terraform {
+  backend "consul" {
+    address = "consul.example.com:8500"
+    scheme  = "https"
+    path    = "terraform/prod/network"
+  }
+}
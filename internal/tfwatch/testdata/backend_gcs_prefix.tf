@@ -0,0 +1,6 @@
+terraform {
+  backend "gcs" {
+    bucket = "my-gcs-bucket"
+    prefix = "env/prod"
+  }
+}
@@ -0,0 +1,6 @@
+terraform {
+  backend "oss" {
+    bucket = "acme-oss-state"
+    prefix = "network"
+  }
+}
@@ -0,0 +1,6 @@
+terraform {
+  backend "artifactory" {
+    url  = "https://artifactory.example.com/artifactory"
+    repo = "terraform-state"
+  }
+}
@@ -0,0 +1,68 @@
+package tfwatch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// textfileExporter writes metrics in the Prometheus text format to a file
+// for node_exporter's textfile collector. Every export replaces the file.
+type textfileExporter struct {
+	path string
+
+	writeMu sync.Mutex // serializes exports
+	baseExporter
+}
+
+// NewTextfileExporter creates an exporter that writes to path, which must
+// end in ".prom" for the textfile collector to read it.
+func NewTextfileExporter(path string) (sdkmetric.Exporter, error) {
+	if filepath.Ext(path) != ".prom" {
+		return nil, fmt.Errorf("textfile %q must have the .prom extension", path)
+	}
+	return &textfileExporter{path: path}, nil
+}
+
+// Export writes rm to a temporary file next to the target and renames it
+// into place, so that a scrape never reads a partial file. The temporary
+// name does not end in ".prom", so the collector ignores it.
+func (e *textfileExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	if err := e.closed(); err != nil {
+		return err
+	}
+
+	dir, base := filepath.Split(e.path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+strings.TrimSuffix(base, ".prom")+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	// CreateTemp uses 0600; node_exporter usually runs as another user.
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := WritePrometheus(tmp, rm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics to %s: %w", e.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics to %s: %w", e.path, err)
+	}
+	if err := os.Rename(tmp.Name(), e.path); err != nil {
+		return fmt.Errorf("failed to write metrics to %s: %w", e.path, err)
+	}
+	return nil
+}
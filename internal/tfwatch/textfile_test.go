@@ -0,0 +1,49 @@
+package tfwatch
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTextfileExporter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tfwatch.prom")
+	if err := os.WriteFile(path, []byte("stale\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exporter, err := NewTextfileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm := testResourceMetrics(t)
+	if err := exporter.Export(context.Background(), rm); err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+
+	var want bytes.Buffer
+	if err := WritePrometheus(&want, rm); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want.String() {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want.String())
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("expected a world-readable file, got %v, %v", info.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestNewTextfileExporter_Extension(t *testing.T) {
+	if _, err := NewTextfileExporter("metrics.txt"); err == nil {
+		t.Error("expected an error for a file the textfile collector ignores")
+	}
+}
@@ -0,0 +1,159 @@
+package tfwatch
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// newTLSConfig returns the client TLS configuration for cfg. Without a CA
+// file the system roots are trusted; without a client certificate none is
+// offered.
+//
+// The CA file and the client key pair are re-read whenever their
+// modification time changes, so certificates rotated on disk are used for
+// the next connection without restarting a long-running "tfwatch serve".
+// If a changed file cannot be loaded, for example because it is only half
+// written, the previous material is kept and a warning is logged.
+func newTLSConfig(cfg ExporterConfig) (*tls.Config, error) {
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, errors.New("a client certificate and key must be given together")
+	}
+
+	conf := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.ServerName}
+	r := &certReloader{caFile: cfg.CAFile, certFile: cfg.ClientCert, keyFile: cfg.ClientKey, serverName: cfg.ServerName}
+	if r.serverName == "" {
+		// What gRPC and net/http verify against by default. The connection
+		// state does not carry it for IP addresses, which are not sent as SNI.
+		host, _, err := net.SplitHostPort(cfg.Endpoint)
+		if err != nil {
+			host = cfg.Endpoint
+		}
+		r.serverName = host
+	}
+
+	if r.certFile != "" {
+		if err := r.reloadCert(); err != nil {
+			return nil, err
+		}
+		conf.GetClientCertificate = r.clientCertificate
+	}
+	if r.caFile != "" {
+		if err := r.reloadCA(); err != nil {
+			return nil, err
+		}
+		// RootCAs cannot be swapped on a live tls.Config, so the chain is
+		// verified in VerifyConnection against the current pool instead.
+		// This is the same check the standard library would make.
+		conf.InsecureSkipVerify = true
+		conf.VerifyConnection = r.verifyConnection
+	}
+	return conf, nil
+}
+
+// certReloader holds TLS material loaded from files and reloads it when the
+// files change.
+type certReloader struct {
+	caFile, certFile, keyFile string
+	serverName                string
+
+	mu      sync.Mutex
+	pool    *x509.CertPool
+	caMod   time.Time
+	cert    *tls.Certificate
+	certMod time.Time // latest modification time of the cert and key files
+}
+
+// clientCertificate implements tls.Config.GetClientCertificate.
+func (r *certReloader) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if mod, err := latestModTime(r.certFile, r.keyFile); err == nil && !mod.Equal(r.certMod) {
+		if err := r.reloadCert(); err != nil {
+			log.Printf("Warning: keeping previous OTLP client certificate: %v", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// verifyConnection implements tls.Config.VerifyConnection, verifying the
+// server's chain and name against the current CA pool.
+func (r *certReloader) verifyConnection(cs tls.ConnectionState) error {
+	r.mu.Lock()
+	if mod, err := latestModTime(r.caFile); err == nil && !mod.Equal(r.caMod) {
+		if err := r.reloadCA(); err != nil {
+			log.Printf("Warning: keeping previous OTLP CA certificates: %v", err)
+		}
+	}
+	pool := r.pool
+	r.mu.Unlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       r.serverName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// reloadCert loads the client key pair. The caller holds r.mu, except
+// during construction.
+func (r *certReloader) reloadCert() error {
+	mod, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	r.cert, r.certMod = &cert, mod
+	return nil
+}
+
+// reloadCA loads the CA pool. The caller holds r.mu, except during
+// construction.
+func (r *certReloader) reloadCA() error {
+	mod, err := latestModTime(r.caFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no PEM certificates found in %s", r.caFile)
+	}
+	r.pool, r.caMod = pool, mod
+	return nil
+}
+
+// latestModTime returns the most recent modification time of files.
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,372 @@
+package tfwatch
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// testCA is a certificate authority generated for a test.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tfwatch test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key signed by the CA. Server
+// certificates are valid for 127.0.0.1 and collector.test.
+func (ca *testCA) issue(t *testing.T, cn string, server bool) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.DNSNames = []string{"collector.test"}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// serverTLS returns a server configuration presenting a certificate from
+// ca and requiring client certificates signed by it.
+func (ca *testCA) serverTLS(t *testing.T) *tls.Config {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "collector", true)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// writeFile writes data to name in dir and returns the path.
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fakeGRPCReceiver is an OTLP/gRPC metrics service recording the common
+// name of each client certificate.
+type fakeGRPCReceiver struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+
+	mu      sync.Mutex
+	clients []string
+}
+
+func (f *fakeGRPCReceiver) Export(ctx context.Context, _ *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+			f.clients = append(f.clients, info.State.PeerCertificates[0].Subject.CommonName)
+		}
+	}
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func startGRPCReceiver(t *testing.T, conf *tls.Config) (*fakeGRPCReceiver, string) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver := &fakeGRPCReceiver{}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(conf)))
+	colmetricpb.RegisterMetricsServiceServer(server, receiver)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+	return receiver, lis.Addr().String()
+}
+
+func TestNewExporter_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.pem", ca.pem)
+	certPEM, keyPEM := ca.issue(t, "tfwatch", false)
+	certFile := writeFile(t, dir, "client.pem", certPEM)
+	keyFile := writeFile(t, dir, "client-key.pem", keyPEM)
+	otherCA := newTestCA(t)
+	otherCAFile := writeFile(t, dir, "other-ca.pem", otherCA.pem)
+
+	tests := []struct {
+		name     string
+		protocol string
+		cfg      ExporterConfig
+		wantErr  bool
+	}{
+		{name: "grpc", protocol: ProtocolGRPC, cfg: ExporterConfig{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile}},
+		{name: "grpc server name", protocol: ProtocolGRPC, cfg: ExporterConfig{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile, ServerName: "collector.test"}},
+		{name: "grpc wrong server name", protocol: ProtocolGRPC, cfg: ExporterConfig{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile, ServerName: "other.test"}, wantErr: true},
+		{name: "grpc untrusted server", protocol: ProtocolGRPC, cfg: ExporterConfig{CAFile: otherCAFile, ClientCert: certFile, ClientKey: keyFile}, wantErr: true},
+		{name: "grpc no client certificate", protocol: ProtocolGRPC, cfg: ExporterConfig{CAFile: caFile}, wantErr: true},
+		{name: "http/protobuf", protocol: ProtocolHTTPProtobuf, cfg: ExporterConfig{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile}},
+		{name: "http/json", protocol: ProtocolHTTPJSON, cfg: ExporterConfig{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile, ServerName: "collector.test"}},
+		{name: "http/json untrusted server", protocol: ProtocolHTTPJSON, cfg: ExporterConfig{CAFile: otherCAFile, ClientCert: certFile, ClientKey: keyFile}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				endpoint string
+				clients  func() []string
+			)
+			if tt.protocol == ProtocolGRPC {
+				receiver, addr := startGRPCReceiver(t, ca.serverTLS(t))
+				endpoint = addr
+				clients = func() []string {
+					receiver.mu.Lock()
+					defer receiver.mu.Unlock()
+					return receiver.clients
+				}
+			} else {
+				var mu sync.Mutex
+				var seen []string
+				server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					seen = append(seen, r.TLS.PeerCertificates[0].Subject.CommonName)
+					mu.Unlock()
+					(&fakeReceiver{}).ServeHTTP(w, r)
+				}))
+				server.TLS = ca.serverTLS(t)
+				server.StartTLS()
+				defer server.Close()
+				endpoint = strings.TrimPrefix(server.URL, "https://")
+				clients = func() []string {
+					mu.Lock()
+					defer mu.Unlock()
+					return seen
+				}
+			}
+
+			cfg := tt.cfg
+			cfg.Protocol = tt.protocol
+			cfg.Endpoint = endpoint
+			// The gRPC exporter retries failed handshakes until the timeout.
+			cfg.Timeout = 2 * time.Second
+			if tt.wantErr {
+				cfg.Timeout = 300 * time.Millisecond
+			}
+			ctx := context.Background()
+			exporter, err := NewExporter(ctx, cfg)
+			if err != nil {
+				t.Fatalf("NewExporter() error: %v", err)
+			}
+			defer exporter.Shutdown(ctx)
+
+			err = exporter.Export(ctx, testResourceMetrics(t))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected export to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Export() error: %v", err)
+			}
+			if got := clients(); len(got) != 1 || got[0] != "tfwatch" {
+				t.Errorf("expected the collector to see client certificate tfwatch, got %v", got)
+			}
+		})
+	}
+}
+
+func TestNewExporter_TLSInvalid(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := writeFile(t, dir, "ca.pem", []byte("not a certificate"))
+
+	tests := []struct {
+		name    string
+		cfg     ExporterConfig
+		wantErr string
+	}{
+		{"insecure with CA", ExporterConfig{Endpoint: "localhost:4317", Insecure: true, CAFile: notPEM}, "TLS options require a secure connection"},
+		{"cert without key", ExporterConfig{Endpoint: "localhost:4317", ClientCert: notPEM}, "must be given together"},
+		{"missing CA", ExporterConfig{Endpoint: "localhost:4317", CAFile: filepath.Join(dir, "missing.pem")}, "no such file"},
+		{"invalid CA", ExporterConfig{Endpoint: "localhost:4317", CAFile: notPEM}, "no PEM certificates"},
+		{"invalid key pair", ExporterConfig{Endpoint: "localhost:4317", ClientCert: notPEM, ClientKey: notPEM}, "failed to load client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExporter(context.Background(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNewExporter_CertificateReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	caFile := writeFile(t, dir, "ca.pem", ca.pem)
+	certPEM, keyPEM := ca.issue(t, "before", false)
+	certFile := writeFile(t, dir, "client.pem", certPEM)
+	keyFile := writeFile(t, dir, "client-key.pem", keyPEM)
+
+	var (
+		mu      sync.Mutex
+		clients []string
+	)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		clients = append(clients, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+		(&fakeReceiver{}).ServeHTTP(w, r)
+	}))
+	// Every export then makes a new handshake, as happens to a
+	// long-running serve when the collector drops idle connections.
+	server.Config.SetKeepAlivesEnabled(false)
+	server.TLS = ca.serverTLS(t)
+	server.StartTLS()
+	defer server.Close()
+
+	ctx := context.Background()
+	exporter, err := NewExporter(ctx, ExporterConfig{
+		Protocol:   ProtocolHTTPJSON,
+		Endpoint:   strings.TrimPrefix(server.URL, "https://"),
+		CAFile:     caFile,
+		ClientCert: certFile,
+		ClientKey:  keyFile,
+	})
+	if err != nil {
+		t.Fatalf("NewExporter() error: %v", err)
+	}
+	defer exporter.Shutdown(ctx)
+	if err := exporter.Export(ctx, testResourceMetrics(t)); err != nil {
+		t.Fatalf("Export() error: %v", err)
+	}
+
+	// Rotate the client certificate in place.
+	certPEM, keyPEM = ca.issue(t, "after", false)
+	writeFile(t, dir, "client.pem", certPEM)
+	writeFile(t, dir, "client-key.pem", keyPEM)
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.Export(ctx, testResourceMetrics(t)); err != nil {
+		t.Fatalf("Export() after rotation error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(clients) != 2 || clients[0] != "before" || clients[1] != "after" {
+		t.Errorf("expected client certificates [before after], got %v", clients)
+	}
+}
+
+func TestCertReloader_KeepsPreviousOnError(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, "good", false)
+	certFile := writeFile(t, dir, "client.pem", certPEM)
+	keyFile := writeFile(t, dir, "client-key.pem", keyPEM)
+
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reloadCert(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A half-written rotation must not break the next connection.
+	writeFile(t, dir, "client.pem", []byte("-----BEGIN CERTIFICATE-----\n"))
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := r.clientCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.Subject.CommonName != "good" {
+		t.Errorf("expected the previous certificate, got %q", leaf.Subject.CommonName)
+	}
+}
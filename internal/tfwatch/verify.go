@@ -0,0 +1,244 @@
+package tfwatch
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Statuses of a ProviderInstall.
+const (
+	// InstallVerified is an installed package whose h1: hash is in the lock file.
+	InstallVerified = "verified"
+	// InstallMismatch is an installed package of a locked version whose h1:
+	// hash is not in the lock file: it was modified or replaced.
+	InstallMismatch = "mismatch"
+	// InstallMissing is a locked provider version with no installed package.
+	InstallMissing = "missing"
+	// InstallExtra is an installed package of a provider or version the lock
+	// file does not pin, e.g. one left behind by an upgrade.
+	InstallExtra = "extra"
+)
+
+// ProviderInstall is the result of verifying one provider package in
+// .terraform/providers, or a locked provider version with none.
+type ProviderInstall struct {
+	Source   string `json:"source" yaml:"source"`
+	Version  string `json:"version" yaml:"version"`
+	Platform string `json:"platform,omitempty" yaml:"platform,omitempty"` // "" if missing
+	Status   string `json:"status" yaml:"status"`
+	Hash     string `json:"hash,omitempty" yaml:"hash,omitempty"` // computed h1: hash; "" if missing
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// OK reports whether the install agrees with the lock file. Extra installs
+// are reported but do not fail: Terraform ignores them.
+func (i ProviderInstall) OK() bool {
+	return i.Status == InstallVerified || i.Status == InstallExtra
+}
+
+// VerifyProviders compares the provider packages terraform init installed
+// below .terraform/providers/<host>/<namespace>/<type>/<version>/<os_arch>
+// in directory with .terraform.lock.hcl. Each package of a locked version
+// is hashed like Terraform's h1: scheme and must match one of its lock file
+// hashes. Nothing is fetched; a package that cannot be read is a mismatch.
+// The result lists the installed packages, sorted, followed by the locked
+// versions that have none. A directory without a lock file is an error.
+func VerifyProviders(directory string) ([]ProviderInstall, error) {
+	if _, err := os.Stat(filepath.Join(directory, ".terraform.lock.hcl")); err != nil {
+		return nil, fmt.Errorf("cannot verify providers: %w", err)
+	}
+	providers, err := NewParser(directory).ParseProviders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse providers: %w", err)
+	}
+	installed, err := installedPackages(filepath.Join(directory, ".terraform", "providers"))
+	if err != nil {
+		return nil, err
+	}
+
+	locked := make(map[string]Provider, len(providers))
+	for _, p := range providers {
+		locked[strings.ToLower(p.Source)] = p
+	}
+
+	var results []ProviderInstall
+	found := map[string]bool{}
+	for _, pkg := range installed {
+		install := ProviderInstall{Source: pkg.source, Version: pkg.version, Platform: pkg.platform}
+		p, ok := locked[strings.ToLower(pkg.source)]
+		if !ok || p.Version != pkg.version {
+			install.Status = InstallExtra
+			results = append(results, install)
+			continue
+		}
+		found[strings.ToLower(p.Source)] = true
+
+		install.Hash, err = PackageHash(pkg.dir)
+		switch {
+		case err != nil:
+			install.Status, install.Error = InstallMismatch, err.Error()
+		case slices.Contains(p.Hashes, install.Hash):
+			install.Status = InstallVerified
+		default:
+			install.Status = InstallMismatch
+		}
+		results = append(results, install)
+	}
+
+	for _, p := range providers {
+		if !found[strings.ToLower(p.Source)] {
+			results = append(results, ProviderInstall{Source: p.Source, Version: p.Version, Status: InstallMissing})
+		}
+	}
+	return results, nil
+}
+
+// providerPackage is an unpacked provider package in .terraform/providers.
+type providerPackage struct {
+	source, version, platform, dir string
+}
+
+// installedPackages returns the package directories five levels below root,
+// sorted by source, version and platform. A missing root has none. Entries
+// may be symlinks into the plugin cache.
+func installedPackages(root string) ([]providerPackage, error) {
+	matches, err := filepath.Glob(filepath.Join(root, "*", "*", "*", "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []providerPackage
+	for _, dir := range matches {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		pkgs = append(pkgs, providerPackage{
+			source:   strings.Join(parts[:3], "/"),
+			version:  parts[3],
+			platform: parts[4],
+			dir:      dir,
+		})
+	}
+	slices.SortFunc(pkgs, func(a, b providerPackage) int {
+		return cmp.Or(cmp.Compare(a.source, b.source), cmp.Compare(a.version, b.version), cmp.Compare(a.platform, b.platform))
+	})
+	return pkgs, nil
+}
+
+// PackageHash returns the h1: hash of an unpacked provider package, as
+// Terraform records it in the lock file: the "dirhash" Hash1 of Go modules.
+// Every regular file below dir is listed as "<sha256 hex>  <slash path>\n",
+// sorted by path, and the SHA-256 of that listing is encoded in base64. A
+// symlink at dir itself is followed, as Terraform does for the plugin cache.
+func PackageHash(dir string) (string, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	slices.Sort(files)
+
+	summary := sha256.New()
+	for _, name := range files {
+		if strings.Contains(name, "\n") {
+			return "", errors.New("file names with newlines cannot be hashed")
+		}
+		sum, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", sum, name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+func fileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// VerifyRoots runs VerifyProviders for every directory in dirs and prints
+// one line per package, with a "=== dir ===" header per root when there is
+// more than one, followed by the totals. It returns the number of roots that
+// could not be verified and the number of installs that are not OK.
+func VerifyRoots(root string, dirs []string) (failed, problems int) {
+	counts := map[string]int{}
+	for _, dir := range dirs {
+		rel := relativeDir(root, dir)
+		if len(dirs) > 1 {
+			fmt.Printf("\n=== %s ===\n", rel)
+		}
+		installs, err := VerifyProviders(dir)
+		if err != nil {
+			log.Printf("Error: %s: %v", rel, err)
+			failed++
+			continue
+		}
+		if len(installs) == 0 {
+			fmt.Println("\nNo locked or installed providers found.")
+			continue
+		}
+
+		fmt.Printf("\n  %-9s %-45s %-12s %s\n", "STATUS", "PROVIDER", "VERSION", "PLATFORM")
+		for _, i := range installs {
+			counts[i.Status]++
+			if !i.OK() {
+				problems++
+			}
+			fmt.Printf("  %-9s %-45s %-12s %s%s\n", i.Status, i.Source, i.Version, i.Platform, installDetail(i))
+		}
+	}
+
+	fmt.Printf("\n%d verified, %d mismatched, %d missing, %d extra\n",
+		counts[InstallVerified], counts[InstallMismatch], counts[InstallMissing], counts[InstallExtra])
+	return failed, problems
+}
+
+// installDetail explains a mismatch for VerifyRoots.
+func installDetail(i ProviderInstall) string {
+	switch {
+	case i.Error != "":
+		return " (" + i.Error + ")"
+	case i.Status == InstallMismatch:
+		return " (" + i.Hash + " is not in the lock file)"
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package tfwatch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// nullPackage is an unpacked provider package; nullHash is its h1: hash,
+// computed independently with sha256sum.
+var nullPackage = map[string]string{
+	"terraform-provider-null_v3.2.3_x5": "binary\n",
+	"LICENSE.txt":                       "MIT\n",
+	"docs/README.md":                    "readme\n",
+}
+
+const nullHash = "h1:jOOGPGNAH+e60LmrD72zvQXUUt9VB1n6hfhlNW+vgic="
+
+func TestPackageHash(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, "pkg"), nullPackage)
+
+	got, err := PackageHash(filepath.Join(dir, "pkg"))
+	if err != nil {
+		t.Fatalf("PackageHash() error: %v", err)
+	}
+	if got != nullHash {
+		t.Errorf("expected %s, got %s", nullHash, got)
+	}
+
+	// Installs from the plugin cache are symlinks to the package.
+	if err := os.Symlink(filepath.Join(dir, "pkg"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := PackageHash(filepath.Join(dir, "link")); err != nil || got != nullHash {
+		t.Errorf("expected %s through a symlink, got %s, %v", nullHash, got, err)
+	}
+
+	if _, err := PackageHash(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing package")
+	}
+}
+
+func TestVerifyProviders(t *testing.T) {
+	dir := t.TempDir()
+	providers := filepath.Join(dir, ".terraform", "providers", "registry.terraform.io", "hashicorp")
+	writeFiles(t, filepath.Join(providers, "null", "3.2.3", "linux_amd64"), nullPackage)
+	writeFiles(t, filepath.Join(providers, "null", "3.2.2", "linux_amd64"), nullPackage)
+	writeFiles(t, filepath.Join(providers, "aws", "5.75.1", "linux_amd64"), map[string]string{
+		"terraform-provider-aws_v5.75.1_x5": "tampered\n",
+	})
+	writeFiles(t, filepath.Join(providers, "tls", "4.0.6", "linux_amd64"), map[string]string{
+		"terraform-provider-tls_v4.0.6_x5": "binary\n",
+	})
+	writeFiles(t, dir, map[string]string{
+		".terraform.lock.hcl": `provider "registry.terraform.io/hashicorp/null" {
+  version = "3.2.3"
+  hashes  = ["h1:otherplatform=", "` + nullHash + `", "zh:1111"]
+}
+provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.75.1"
+  hashes  = ["h1:original="]
+}
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+  hashes  = ["h1:random="]
+}
+`,
+	})
+
+	got, err := VerifyProviders(dir)
+	if err != nil {
+		t.Fatalf("VerifyProviders() error: %v", err)
+	}
+	var lines []string
+	for _, i := range got {
+		lines = append(lines, strings.Join([]string{i.Status, i.Source, i.Version, i.Platform}, " "))
+	}
+	want := []string{
+		"mismatch registry.terraform.io/hashicorp/aws 5.75.1 linux_amd64",
+		"extra registry.terraform.io/hashicorp/null 3.2.2 linux_amd64",
+		"verified registry.terraform.io/hashicorp/null 3.2.3 linux_amd64",
+		"extra registry.terraform.io/hashicorp/tls 4.0.6 linux_amd64",
+		"missing registry.terraform.io/hashicorp/random 3.6.0 ",
+	}
+	if !slices.Equal(lines, want) {
+		t.Fatalf("expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(lines, "\n"))
+	}
+	if !strings.HasPrefix(got[0].Hash, "h1:") || got[2].Hash != nullHash {
+		t.Errorf("expected computed hashes, got %+v", got)
+	}
+
+	out := captureStdout(func() {
+		failed, problems := VerifyRoots(dir, []string{dir})
+		if failed != 0 || problems != 2 {
+			t.Errorf("expected 0 failed roots and 2 problems, got %d and %d", failed, problems)
+		}
+	})
+	for _, s := range []string{
+		"is not in the lock file",
+		"1 verified, 1 mismatched, 1 missing, 2 extra",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q, got:\n%s", s, out)
+		}
+	}
+}
+
+func TestVerifyProviders_NoLockFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, filepath.Join(dir, ".terraform", "providers", "registry.terraform.io", "hashicorp", "null", "3.2.3", "linux_amd64"), nullPackage)
+
+	if _, err := VerifyProviders(dir); err == nil || !strings.Contains(err.Error(), "cannot verify providers") {
+		t.Errorf("expected an error without a lock file, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package tfwatch
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/CloudPulse-HQ/tfwatch/internal/semver"
+)
+
+// Satisfies reports whether the module's resolved version satisfies a
+// Terraform version constraint such as "~> 5.1" or ">= 5.0, != 5.0.1".
+// An error is returned if the constraint or the version cannot be parsed,
+// e.g. for Git modules without a version.
+func (m Module) Satisfies(constraint string) (bool, error) {
+	return satisfies(m.Version, constraint)
+}
+
+// CompareVersion compares the module's resolved version with other, returning
+// -1, 0 or +1 as in semver.Version.Compare.
+func (m Module) CompareVersion(other string) (int, error) {
+	return compareVersions(m.Version, other)
+}
+
+// Satisfies reports whether the provider's locked version satisfies a
+// Terraform version constraint such as "~> 5.0".
+func (p Provider) Satisfies(constraint string) (bool, error) {
+	return satisfies(p.Version, constraint)
+}
+
+// CompareVersion compares the provider's locked version with other, returning
+// -1, 0 or +1 as in semver.Version.Compare.
+func (p Provider) CompareVersion(other string) (int, error) {
+	return compareVersions(p.Version, other)
+}
+
+func satisfies(version, constraint string) (bool, error) {
+	cs, err := semver.ParseConstraints(constraint)
+	if err != nil {
+		return false, err
+	}
+	v, err := semver.Parse(version)
+	if err != nil {
+		return false, err
+	}
+	return cs.Check(v), nil
+}
+
+func compareVersions(a, b string) (int, error) {
+	va, err := semver.Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := semver.Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// Constraint returns the provider's effective version constraint: the one
+// recorded in the lock file, which Terraform combines from the
+// required_providers of every module, or else the declared constraints
+// joined. It is "" if no module constrains the version.
+func (p Provider) Constraint() string {
+	if p.LockConstraints != "" {
+		return p.LockConstraints
+	}
+	var declared []string
+	for _, c := range p.Constraints {
+		if c.Constraint != "" && !slices.Contains(declared, c.Constraint) {
+			declared = append(declared, c.Constraint)
+		}
+	}
+	return strings.Join(declared, ", ")
+}
@@ -0,0 +1,77 @@
+package tfwatch
+
+import "testing"
+
+func TestDependencyVersionHelpers(t *testing.T) {
+	tests := []struct {
+		name       string
+		version    string
+		constraint string
+		want       bool
+		wantErr    bool
+	}{
+		{name: "satisfied", version: "5.75.1", constraint: "~> 5.0", want: true},
+		{name: "not satisfied", version: "5.40.0", constraint: ">= 5.60", want: false},
+		{name: "prerelease needs exact match", version: "6.0.0-beta1", constraint: ">= 5.0", want: false},
+		{name: "invalid constraint", version: "5.75.1", constraint: "latest", wantErr: true},
+		{name: "unversioned module", version: "", constraint: ">= 1.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for kind, satisfies := range map[string]func(string) (bool, error){
+				"module":   Module{Name: "m", Version: tt.version}.Satisfies,
+				"provider": Provider{Name: "p", Version: tt.version}.Satisfies,
+			} {
+				got, err := satisfies(tt.constraint)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("%s: expected error=%v, got %v", kind, tt.wantErr, err)
+				}
+				if got != tt.want {
+					t.Errorf("%s: expected %v, got %v", kind, tt.want, got)
+				}
+			}
+		})
+	}
+
+	if c, err := (Provider{Version: "5.9.0"}).CompareVersion("5.10.0"); err != nil || c != -1 {
+		t.Errorf("expected 5.9.0 < 5.10.0, got %d, %v", c, err)
+	}
+	if c, err := (Module{Version: "v2.0"}).CompareVersion("2.0.0"); err != nil || c != 0 {
+		t.Errorf("expected v2.0 == 2.0.0, got %d, %v", c, err)
+	}
+	if _, err := (Module{Version: "main"}).CompareVersion("1.0.0"); err == nil {
+		t.Error("expected error for unparsable version")
+	}
+}
+
+func TestProvider_Constraint(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		want     string
+	}{
+		{name: "unpinned", provider: Provider{Constraints: []ProviderConstraint{{Module: ""}}}, want: ""},
+		{
+			name: "lock file wins",
+			provider: Provider{LockConstraints: "~> 5.0, >= 5.10.0", Constraints: []ProviderConstraint{
+				{Constraint: "~> 5.0"}, {Module: "network", Constraint: ">= 5.10.0"},
+			}},
+			want: "~> 5.0, >= 5.10.0",
+		},
+		{
+			name: "declared without lock file",
+			provider: Provider{Constraints: []ProviderConstraint{
+				{Constraint: ">= 5.0"}, {Module: "network"}, {Module: "dns", Constraint: ">= 5.0"}, {Module: "eks", Constraint: "< 6.0"},
+			}},
+			want: ">= 5.0, < 6.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.provider.Constraint(); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package tfwatch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is how long a root must be quiet before Watcher reports it.
+// "terraform init" rewrites the lock file and modules.json in several steps;
+// waiting for the burst to end avoids scanning half-written files.
+const DefaultDebounce = 2 * time.Second
+
+// Watcher reports root modules whose dependency inputs change: *.tf files and
+// .terraform.lock.hcl in the root, and .terraform/modules/modules.json.
+// It watches the given roots only; roots created later are not picked up.
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	roots    map[string]bool // cleaned root directories
+	debounce time.Duration
+}
+
+// NewWatcher starts watching roots. A debounce of zero uses DefaultDebounce.
+func NewWatcher(roots []string, debounce time.Duration) (*Watcher, error) {
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	w := &Watcher{fs: fsw, roots: make(map[string]bool, len(roots)), debounce: debounce}
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		if err := fsw.Add(root); err != nil {
+			fsw.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", root, err)
+		}
+		w.roots[root] = true
+		w.watchCache(root)
+	}
+	return w, nil
+}
+
+// watchCache adds watches for the .terraform and .terraform/modules
+// directories of root, if they exist. Both are created by "terraform init",
+// possibly after the watch started.
+func (w *Watcher) watchCache(root string) {
+	for _, dir := range []string{
+		filepath.Join(root, ".terraform"),
+		filepath.Join(root, ".terraform", "modules"),
+	} {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			if err := w.fs.Add(dir); err != nil {
+				log.Printf("Warning: failed to watch %s: %v", dir, err)
+			}
+		}
+	}
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// Run calls changed with the root directory every time one of its inputs
+// changes and then stays unchanged for the debounce period, until ctx is
+// cancelled or the Watcher is closed. Calls are serialized: a root that
+// changes while changed is running for it is reported again afterwards, and
+// other roots wait their turn. Events keep being consumed meanwhile, so long
+// scans do not overflow the kernel queue.
+func (w *Watcher) Run(ctx context.Context, changed func(root string)) {
+	timers := make(map[string]*time.Timer)
+	defer func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
+
+	fired := make(chan string)
+	done := make(chan struct{})
+	var queue []string
+	queued := make(map[string]bool)
+	busy := false
+
+	dispatch := func() {
+		if busy || len(queue) == 0 {
+			return
+		}
+		root := queue[0]
+		queue = queue[1:]
+		delete(queued, root)
+		busy = true
+		go func() {
+			changed(root)
+			done <- struct{}{}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if busy {
+				<-done
+			}
+			return
+
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			root, ok := w.rootOf(ev)
+			if !ok {
+				continue
+			}
+			if t, ok := timers[root]; ok {
+				t.Reset(w.debounce)
+				continue
+			}
+			timers[root] = time.AfterFunc(w.debounce, func() {
+				select {
+				case fired <- root:
+				case <-ctx.Done():
+				}
+			})
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			// An overflow loses events; rescanning everything is the
+			// only way to be sure nothing was missed.
+			log.Printf("Warning: file watcher: %v", err)
+			for root := range w.roots {
+				if !queued[root] {
+					queued[root] = true
+					queue = append(queue, root)
+				}
+			}
+			dispatch()
+
+		case root := <-fired:
+			delete(timers, root)
+			if !queued[root] {
+				queued[root] = true
+				queue = append(queue, root)
+			}
+			dispatch()
+
+		case <-done:
+			busy = false
+			dispatch()
+		}
+	}
+}
+
+// rootOf maps a file system event to the root whose inputs it affects. When
+// .terraform or .terraform/modules appears, it is watched from then on and the
+// root is reported, since files may already have been written to it.
+func (w *Watcher) rootOf(ev fsnotify.Event) (string, bool) {
+	if ev.Op == fsnotify.Chmod {
+		return "", false
+	}
+	path := filepath.Clean(ev.Name)
+	dir, base := filepath.Dir(path), filepath.Base(path)
+
+	switch {
+	case w.roots[dir] && (base == ".terraform.lock.hcl" || strings.HasSuffix(base, ".tf")):
+		return dir, true
+
+	case base == "modules.json" && filepath.Base(dir) == "modules" &&
+		filepath.Base(filepath.Dir(dir)) == ".terraform" && w.roots[filepath.Dir(filepath.Dir(dir))]:
+		return filepath.Dir(filepath.Dir(dir)), true
+
+	case ev.Has(fsnotify.Create) && base == ".terraform" && w.roots[dir]:
+		w.watchCache(dir)
+		return dir, true
+
+	case ev.Has(fsnotify.Create) && base == "modules" && filepath.Base(dir) == ".terraform" && w.roots[filepath.Dir(dir)]:
+		w.watchCache(filepath.Dir(dir))
+		return filepath.Dir(dir), true
+	}
+	return "", false
+}
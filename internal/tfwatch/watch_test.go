@@ -0,0 +1,125 @@
+package tfwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatcher_RootOf(t *testing.T) {
+	root := t.TempDir()
+	w, err := NewWatcher([]string{root}, time.Second)
+	if err != nil {
+		t.Fatalf("NewWatcher() error: %v", err)
+	}
+	defer w.Close()
+
+	tests := []struct {
+		name   string
+		event  fsnotify.Event
+		wantOK bool
+	}{
+		{"tf file", fsnotify.Event{Name: filepath.Join(root, "main.tf"), Op: fsnotify.Write}, true},
+		{"lock file", fsnotify.Event{Name: filepath.Join(root, ".terraform.lock.hcl"), Op: fsnotify.Create}, true},
+		{"lock file removed", fsnotify.Event{Name: filepath.Join(root, ".terraform.lock.hcl"), Op: fsnotify.Remove}, true},
+		{"modules.json", fsnotify.Event{Name: filepath.Join(root, ".terraform", "modules", "modules.json"), Op: fsnotify.Write}, true},
+		{".terraform created", fsnotify.Event{Name: filepath.Join(root, ".terraform"), Op: fsnotify.Create}, true},
+		{"chmod only", fsnotify.Event{Name: filepath.Join(root, "main.tf"), Op: fsnotify.Chmod}, false},
+		{"other file", fsnotify.Event{Name: filepath.Join(root, "README.md"), Op: fsnotify.Write}, false},
+		{"editor swap file", fsnotify.Event{Name: filepath.Join(root, ".main.tf.swp"), Op: fsnotify.Write}, false},
+		{"provider cache", fsnotify.Event{Name: filepath.Join(root, ".terraform", "providers"), Op: fsnotify.Create}, false},
+		{"tf file in subdirectory", fsnotify.Event{Name: filepath.Join(root, "modules", "vpc", "main.tf"), Op: fsnotify.Write}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := w.rootOf(tt.event)
+			if ok != tt.wantOK {
+				t.Fatalf("rootOf(%s) ok = %v, want %v", tt.event, ok, tt.wantOK)
+			}
+			if ok && got != root {
+				t.Errorf("rootOf(%s) = %q, want %q", tt.event, got, root)
+			}
+		})
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	base := t.TempDir()
+	network := filepath.Join(base, "network")
+	dns := filepath.Join(base, "dns")
+	for _, dir := range []string{network, dns} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewWatcher([]string{network, dns}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewWatcher() error: %v", err)
+	}
+	defer w.Close()
+
+	changed := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		w.Run(ctx, func(root string) { changed <- root })
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	write := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("# changed\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-changed:
+			if got != want {
+				t.Fatalf("changed %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	expectNone := func() {
+		t.Helper()
+		select {
+		case got := <-changed:
+			t.Fatalf("unexpected change in %q", got)
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+
+	// A burst of writes, as from "terraform init", is reported once.
+	for range 5 {
+		write(filepath.Join(network, ".terraform.lock.hcl"))
+		write(filepath.Join(network, "main.tf"))
+	}
+	expect(network)
+	expectNone()
+
+	// .terraform/modules is created after the watch started.
+	write(filepath.Join(dns, ".terraform", "modules", "modules.json"))
+	expect(dns)
+	expectNone()
+	write(filepath.Join(dns, ".terraform", "modules", "modules.json"))
+	expect(dns)
+
+	write(filepath.Join(network, "README.md"))
+	expectNone()
+}